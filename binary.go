@@ -0,0 +1,149 @@
+package lscolors
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// binaryVersion is the version of the encoding used by MarshalBinary.
+const binaryVersion = 1
+
+var errBinaryTruncated = errors.New("lscolors: invalid binary encoding: truncated data")
+
+// MarshalBinary encodes the LS_COLORS rules of c (the same rules that are
+// serialized by String) into a compact binary form that can be decoded
+// faster than parsing the equivalent string. The encoding starts with a
+// version byte followed by the indicator sequences and then the extension
+// and glob rules, all length prefixed.
+func (c *LSColors) MarshalBinary() ([]byte, error) {
+	n := 1 + 3*binary.MaxVarintLen64
+	c.eachRule(func(e *ColorExtension) {
+		n += 2*binary.MaxVarintLen64 + len(e.Ext) + len(e.Seq)
+	})
+	b := make([]byte, 0, n)
+	b = append(b, binaryVersion)
+	for _, e := range c.indicators() {
+		b = appendBinaryString(b, e.Seq)
+	}
+	for _, exts := range [...][]ColorExtension{c.Exts, c.Globs} {
+		b = binary.AppendUvarint(b, uint64(len(exts)))
+		for _, e := range exts {
+			b = appendBinaryString(b, e.Ext)
+			b = appendBinaryString(b, e.Seq)
+		}
+	}
+	return b, nil
+}
+
+func appendBinaryString(b []byte, s string) []byte {
+	b = binary.AppendUvarint(b, uint64(len(s)))
+	return append(b, s...)
+}
+
+// UnmarshalBinary decodes data produced by MarshalBinary into c replacing
+// all of its LS_COLORS rules. The escape codes (LeftCode, RightCode and
+// EndCode), which are not encoded, are cleared. Options such as
+// HiddenColor are not modified.
+func (c *LSColors) UnmarshalBinary(data []byte) error {
+	if len(data) == 0 {
+		return errBinaryTruncated
+	}
+	if data[0] != binaryVersion {
+		return fmt.Errorf("lscolors: unsupported binary encoding version: %d", data[0])
+	}
+	d := binaryDecoder{s: string(data[1:])} // single allocation for all strings
+
+	var ls LSColors
+	for i, e := range ls.indicators() {
+		if seq := d.string(); seq != "" {
+			*e = ColorExtension{Ext: indicatorKeys[i], Seq: seq}
+		}
+	}
+	ls.Exts = d.exts()
+	ls.Globs = d.exts()
+	if d.err != nil {
+		return d.err
+	}
+	if len(d.s) != 0 {
+		return errors.New("lscolors: invalid binary encoding: trailing data")
+	}
+	if err := ls.Validate(); err != nil {
+		return err
+	}
+	if !slices.IsSortedFunc(ls.Exts, compareExt) {
+		return errors.New("lscolors: invalid binary encoding: extensions are not sorted")
+	}
+	ls.finalized = true
+
+	c.DI, c.FI, c.LN, c.PI, c.SO = ls.DI, ls.FI, ls.LN, ls.PI, ls.SO
+	c.BD, c.CD, c.OR, c.MI, c.EX = ls.BD, ls.CD, ls.OR, ls.MI, ls.EX
+	c.TW, c.NO, c.ST, c.OW, c.RS = ls.TW, ls.NO, ls.ST, ls.OW, ls.RS
+	c.Exts, c.Globs = ls.Exts, ls.Globs
+	c.LeftCode, c.RightCode, c.EndCode = "", "", ""
+	c.extOrder = nil
+	c.Names = nil
+	for _, e := range c.Exts {
+		c.setName(e.Ext, e.Seq)
+	}
+	c.invalid = nil
+	c.shadowed = nil
+	c.finalized = true
+	return nil
+}
+
+type binaryDecoder struct {
+	s   string
+	err error
+}
+
+func (d *binaryDecoder) uvarint() uint64 {
+	if d.err != nil {
+		return 0
+	}
+	var x uint64
+	var shift uint
+	for i := 0; i < len(d.s) && i < binary.MaxVarintLen64; i++ {
+		b := d.s[i]
+		if b < 0x80 {
+			d.s = d.s[i+1:]
+			return x | uint64(b)<<shift
+		}
+		x |= uint64(b&0x7f) << shift
+		shift += 7
+	}
+	d.err = errBinaryTruncated
+	return 0
+}
+
+func (d *binaryDecoder) string() string {
+	n := d.uvarint()
+	if d.err != nil {
+		return ""
+	}
+	if n > uint64(len(d.s)) {
+		d.err = errBinaryTruncated
+		return ""
+	}
+	s := d.s[:n]
+	d.s = d.s[n:]
+	return s
+}
+
+func (d *binaryDecoder) exts() []ColorExtension {
+	n := d.uvarint()
+	if d.err != nil || n == 0 {
+		return nil
+	}
+	// Each rule requires at least two bytes
+	if n > uint64(len(d.s))/2 {
+		d.err = errBinaryTruncated
+		return nil
+	}
+	exts := make([]ColorExtension, n)
+	for i := range exts {
+		exts[i] = ColorExtension{Ext: d.string(), Seq: d.string()}
+	}
+	return exts
+}
@@ -0,0 +1,141 @@
+package lscolors
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMarshalBinary(t *testing.T) {
+	tests := []string{
+		"di=01;34",
+		"di=01;34:rs=0:*.go=32:.git*=1",
+		strings.Join(hugeLSCOLOR, ":"),
+	}
+	for _, colors := range tests {
+		ls, err := ParseLSColors(colors)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := ls.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got LSColors
+		if err := got.UnmarshalBinary(data); err != nil {
+			t.Fatal(err)
+		}
+		if !equalColors(&got, ls) {
+			t.Errorf("round trip of %.32q:\ngot:  %q\nwant: %q", colors, got.String(), ls.String())
+		}
+	}
+}
+
+func TestUnmarshalBinaryInvalid(t *testing.T) {
+	ls, err := ParseLSColors("di=01;34:*.go=32:.git*=1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := ls.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Every truncation must be rejected
+	for i := 0; i < len(data); i++ {
+		var got LSColors
+		if err := got.UnmarshalBinary(data[:i]); err == nil {
+			t.Errorf("UnmarshalBinary(data[:%d]): expected error", i)
+		}
+	}
+	var got LSColors
+	if err := got.UnmarshalBinary(append([]byte{2}, data[1:]...)); err == nil {
+		t.Error("UnmarshalBinary: expected error for unknown version")
+	}
+	if err := got.UnmarshalBinary(append(data, 0)); err == nil {
+		t.Error("UnmarshalBinary: expected error for trailing data")
+	}
+
+	// Sequences are validated
+	bad := &LSColors{DI: ColorExtension{Ext: "di", Seq: "1\x1b[2J"}}
+	data, err = bad.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := got.UnmarshalBinary(data); err == nil {
+		t.Error("UnmarshalBinary: expected error for invalid sequence")
+	}
+}
+
+func TestUnmarshalBinaryShadowed(t *testing.T) {
+	src, err := ParseLSColors("*.c=3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := src.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ls, err := ParseLSColors("*.go=1:*.go=2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ls.ShadowedRules()) == 0 {
+		t.Fatal("expected shadowed rules")
+	}
+	if err := ls.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	if rules := ls.ShadowedRules(); len(rules) != 0 {
+		t.Errorf("ShadowedRules after UnmarshalBinary = %+v; want: none", rules)
+	}
+}
+
+func TestUnmarshalBinaryCodes(t *testing.T) {
+	src, err := ParseLSColors("di=01;34")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := src.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ls, err := ParseLSColors(`lc=\e[:rc=m:ec=\e[0m`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ls.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	if ls.LeftCode != "" || ls.RightCode != "" || ls.EndCode != "" {
+		t.Errorf("UnmarshalBinary kept the escape codes: %q, %q, %q",
+			ls.LeftCode, ls.RightCode, ls.EndCode)
+	}
+}
+
+func BenchmarkUnmarshalBinary(b *testing.B) {
+	ls, err := ParseLSColors(strings.Join(hugeLSCOLOR, ":"))
+	if err != nil {
+		b.Fatal(err)
+	}
+	data, err := ls.MarshalBinary()
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.Run("Binary", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			var ls LSColors
+			if err := ls.UnmarshalBinary(data); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("Parse", func(b *testing.B) {
+		s := ls.String()
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := ParseLSColors(s); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
@@ -0,0 +1,83 @@
+package lscolors
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// bsdDefault is the LSCOLORS value used by BSD ls when it is not set.
+const bsdDefault = "exfxcxdxbxegedabagacad"
+
+// bsdKeys are the LS_COLORS keys of the LSCOLORS fields, in order. The
+// setuid ("su") and setgid ("sg") fields are not supported and are skipped.
+var bsdKeys = [...]string{
+	"di", "ln", "so", "pi", "ex", "bd", "cd", "su", "sg", "tw", "ow",
+}
+
+// ParseLSColorsBSD parses the LSCOLORS environment variable used by BSD and
+// macOS ls. Each field is a pair of foreground and background colors where
+// 'a' through 'h' are black, red, green, brown, blue, magenta, cyan and
+// light grey, 'A' through 'H' are their bold versions and 'x' is the
+// default color. Fields that are omitted use the BSD defaults.
+func ParseLSColorsBSD(s string) (*LSColors, error) {
+	if s == "" {
+		return nil, ErrEmpty
+	}
+	if len(s)%2 != 0 || len(s) > len(bsdDefault) {
+		return nil, fmt.Errorf("lscolors: invalid LSCOLORS length %d: %q", len(s), s)
+	}
+	if len(s) < len(bsdDefault) {
+		s += bsdDefault[len(s):]
+	}
+	ls := LSColors{finalized: true}
+	for i, key := range bsdKeys {
+		seq, ok := bsdSeq(s[i*2], s[i*2+1])
+		if !ok {
+			return nil, fmt.Errorf("lscolors: invalid LSCOLORS field %q for %q",
+				s[i*2:i*2+2], key)
+		}
+		if e := ls.indicator(key); e != nil && seq != "" {
+			*e = ColorExtension{Ext: key, Seq: seq}
+		}
+	}
+	return &ls, nil
+}
+
+// bsdSeq converts the LSCOLORS foreground and background colors fg and bg
+// to an SGR sequence.
+func bsdSeq(fg, bg byte) (string, bool) {
+	var params []string
+	var bold bool
+	for i, c := range [2]byte{fg, bg} {
+		base := 30 + i*10
+		switch {
+		case 'a' <= c && c <= 'h':
+			params = append(params, strconv.Itoa(base+int(c-'a')))
+		case 'A' <= c && c <= 'H':
+			params = append(params, strconv.Itoa(base+int(c-'A')))
+			bold = true
+		case c == 'x':
+		default:
+			return "", false
+		}
+	}
+	if bold {
+		params = append([]string{"01"}, params...)
+	}
+	return strings.Join(params, ";"), true
+}
+
+// NewLSColorsAuto is like NewLSColors but falls back to parsing the BSD
+// LSCOLORS environment variable (see ParseLSColorsBSD) if LS_COLORS is not
+// set. An error wrapping ErrNotSet is returned if neither is set.
+func NewLSColorsAuto() (*LSColors, error) {
+	if clrs, ok := os.LookupEnv("LS_COLORS"); ok {
+		return ParseLSColors(clrs)
+	}
+	if clrs, ok := os.LookupEnv("LSCOLORS"); ok {
+		return ParseLSColorsBSD(clrs)
+	}
+	return nil, fmt.Errorf("%w and neither is LSCOLORS", ErrNotSet)
+}
@@ -0,0 +1,66 @@
+package lscolors
+
+import (
+	"errors"
+	"os"
+	"testing"
+)
+
+func TestParseLSColorsBSD(t *testing.T) {
+	ls, err := ParseLSColorsBSD(bsdDefault)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const want = "di=34:ln=35:pi=33:so=32:bd=34;46:cd=34;43:ex=31:tw=30;42:ow=30;43"
+	if s := ls.String(); s != want {
+		t.Errorf("String() = %q; want: %q", s, want)
+	}
+
+	ls, err = ParseLSColorsBSD("ExGx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ls.DI.Seq != "01;34" || ls.LN.Seq != "01;36" || ls.SO.Seq != "32" {
+		t.Errorf("DI, LN, SO = %q, %q, %q; want: %q, %q, %q",
+			ls.DI.Seq, ls.LN.Seq, ls.SO.Seq, "01;34", "01;36", "32")
+	}
+
+	for _, s := range []string{"", "e", "ezfx", bsdDefault + "ab"} {
+		if _, err := ParseLSColorsBSD(s); err == nil {
+			t.Errorf("ParseLSColorsBSD(%q): expected an error", s)
+		}
+	}
+}
+
+func TestNewLSColorsAuto(t *testing.T) {
+	unset := func(key string) {
+		if v, ok := os.LookupEnv(key); ok {
+			os.Unsetenv(key)
+			t.Cleanup(func() { os.Setenv(key, v) })
+		}
+	}
+	t.Setenv("LS_COLORS", "di=01;31")
+	t.Setenv("LSCOLORS", "Ex")
+
+	ls, err := NewLSColorsAuto()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ls.DI.Seq != "01;31" {
+		t.Errorf("LS_COLORS: DI = %q; want: %q", ls.DI.Seq, "01;31")
+	}
+
+	unset("LS_COLORS")
+	ls, err = NewLSColorsAuto()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ls.DI.Seq != "01;34" {
+		t.Errorf("LSCOLORS: DI = %q; want: %q", ls.DI.Seq, "01;34")
+	}
+
+	unset("LSCOLORS")
+	if _, err := NewLSColorsAuto(); !errors.Is(err, ErrNotSet) {
+		t.Errorf("error = %v; want: %v", err, ErrNotSet)
+	}
+}
@@ -5,7 +5,6 @@ import (
 	"io/fs"
 	"log"
 	"os"
-	"path/filepath"
 	"sync"
 
 	"github.com/charlievieth/fastwalk"
@@ -16,6 +15,19 @@ func init() {
 	log.SetFlags(log.Lshortfile)
 }
 
+var bufPool = sync.Pool{
+	New: func() any {
+		b := make([]byte, 0, 256)
+		return &b
+	},
+}
+
+// appendLine appends the colored path and a trailing newline to b.
+func appendLine(b []byte, ls *lscolors.LSColors, path string, d fs.DirEntry) []byte {
+	b = ls.AppendPath(b, path, d)
+	return append(b, '\n')
+}
+
 func main() {
 
 	var _ = fastwalk.Config{}
@@ -40,13 +52,13 @@ func main() {
 		if d.IsDir() && d.Name() == ".git" {
 			return fastwalk.SkipDir
 		}
-		dir, base := filepath.Split(path)
-		c := ls.MatchEntry(path, d)
+		p := bufPool.Get().(*[]byte)
+		b := appendLine((*p)[:0], ls, path, d)
 		mu.Lock()
-		bw.WriteString(ls.DI.Format(dir))
-		bw.WriteString(c.Format(base))
-		err = bw.WriteByte('\n')
+		_, err = bw.Write(b)
 		mu.Unlock()
+		*p = b
+		bufPool.Put(p)
 		return err
 	})
 	if err != nil {
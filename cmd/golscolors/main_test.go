@@ -0,0 +1,102 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/charlievieth/lscolors"
+)
+
+type entry struct {
+	path string
+	d    fs.DirEntry
+}
+
+// makeTree creates a directory tree with n files and returns its entries.
+func makeTree(tb testing.TB, n int) []entry {
+	root := tb.TempDir()
+	exts := []string{".go", ".txt", ".tar.gz", ".md", ""}
+	for i := 0; i < n; i++ {
+		dir := filepath.Join(root, fmt.Sprintf("d%02d", i%16))
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			tb.Fatal(err)
+		}
+		name := filepath.Join(dir, fmt.Sprintf("file%d%s", i, exts[i%len(exts)]))
+		if err := os.WriteFile(name, nil, 0644); err != nil {
+			tb.Fatal(err)
+		}
+	}
+	var entries []entry
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		entries = append(entries, entry{path, d})
+		return nil
+	})
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return entries
+}
+
+func testLSColors(tb testing.TB) *lscolors.LSColors {
+	ls, err := lscolors.ParseLSColors("di=01;34:fi=0:*.go=32:*.txt=33:*.tar.gz=01;31")
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return ls
+}
+
+// writeLineSplit is how lines were written before appendLine.
+func writeLineSplit(w *bytes.Buffer, ls *lscolors.LSColors, path string, d fs.DirEntry) {
+	dir, base := filepath.Split(path)
+	c := ls.MatchEntry(path, d)
+	w.WriteString(ls.DI.Format(dir))
+	w.WriteString(c.Format(base))
+	w.WriteByte('\n')
+}
+
+func TestAppendLine(t *testing.T) {
+	ls := testLSColors(t)
+	var want bytes.Buffer
+	var got []byte
+	for _, e := range makeTree(t, 100) {
+		writeLineSplit(&want, ls, e.path, e.d)
+		got = appendLine(got, ls, e.path, e.d)
+	}
+	if !bytes.Equal(got, want.Bytes()) {
+		t.Errorf("appendLine output differs:\ngot:  %q\nwant: %q", got, want.Bytes())
+	}
+}
+
+func BenchmarkWriteLine(b *testing.B) {
+	ls := testLSColors(b)
+	entries := makeTree(b, 2000)
+	b.Run("Split", func(b *testing.B) {
+		var w bytes.Buffer
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			w.Reset()
+			for _, e := range entries {
+				writeLineSplit(&w, ls, e.path, e.d)
+			}
+		}
+	})
+	b.Run("Append", func(b *testing.B) {
+		var w bytes.Buffer
+		var buf []byte
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			w.Reset()
+			for _, e := range entries {
+				buf = appendLine(buf[:0], ls, e.path, e.d)
+				w.Write(buf)
+			}
+		}
+	})
+}
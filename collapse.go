@@ -0,0 +1,106 @@
+package lscolors
+
+import (
+	"io"
+	"io/fs"
+)
+
+// A CollapseWriter writes colored entries to an io.Writer but only emits
+// an escape sequence when the color changes, instead of wrapping every
+// entry in its own color and reset. This reduces the size of the output
+// when many consecutive entries, such as a directory of log files, have
+// the same color. Flush must be called after the last entry to reset the
+// color.
+type CollapseWriter struct {
+	w   io.Writer
+	c   *LSColors
+	cur string // the active sequence or "" if none
+	buf []byte
+}
+
+// NewCollapseWriter returns a CollapseWriter that writes entries colored by
+// c to w.
+func NewCollapseWriter(w io.Writer, c *LSColors) *CollapseWriter {
+	return &CollapseWriter{w: w, c: c}
+}
+
+// WriteEntry writes the name of d colored by the type of the file at path.
+func (w *CollapseWriter) WriteEntry(path string, d fs.DirEntry) error {
+	return w.WriteColored(w.c.MatchEntry(path, d), d.Name())
+}
+
+// WriteColored writes s colored by e.
+func (w *CollapseWriter) WriteColored(e *ColorExtension, s string) error {
+	b := w.setColor(w.buf[:0], e.Seq)
+	b = append(b, s...)
+	return w.write(b)
+}
+
+// WriteSeparator writes the separator s, such as a newline, between
+// entries. The current color is kept active, so that an entry with the
+// same color that follows does not need a new escape sequence, unless it
+// sets a background color, which would be painted behind s.
+func (w *CollapseWriter) WriteSeparator(s string) error {
+	b := w.buf[:0]
+	if w.cur != "" && hasBackground(w.cur) {
+		b = w.setColor(b, "")
+	}
+	b = append(b, s...)
+	return w.write(b)
+}
+
+// Flush resets the color, if one is active. It does not flush the
+// underlying io.Writer.
+func (w *CollapseWriter) Flush() error {
+	return w.write(w.setColor(w.buf[:0], ""))
+}
+
+// setColor appends the escape sequence that changes the active color to
+// seq, if it is not already active.
+func (w *CollapseWriter) setColor(b []byte, seq string) []byte {
+	switch {
+	case seq == w.cur:
+		return b
+	case seq == "":
+		b = append(b, resetSeq...)
+	case w.cur == "":
+		b = append(b, "\x1b["...)
+		b = append(b, seq...)
+		b = append(b, 'm')
+	default:
+		// Reset the attributes of the previous color as well.
+		b = append(b, "\x1b[0;"...)
+		b = append(b, seq...)
+		b = append(b, 'm')
+	}
+	w.cur = seq
+	return b
+}
+
+func (w *CollapseWriter) write(b []byte) error {
+	w.buf = b
+	if len(b) == 0 {
+		return nil
+	}
+	_, err := w.w.Write(b)
+	return err
+}
+
+// hasBackground reports if the SGR sequence seq sets a background color.
+func hasBackground(seq string) bool {
+	params, ok := parseSGR(seq)
+	if !ok {
+		return true // be conservative
+	}
+	for i := 0; i < len(params); i++ {
+		switch p := params[i]; {
+		case 40 <= p && p <= 47, 100 <= p && p <= 107, p == 48:
+			return true
+		case p == 38 && i+1 < len(params) && params[i+1] == 5:
+			i += 2
+		case p == 38 && i+1 < len(params) && params[i+1] == 2:
+			i += 4
+		}
+	}
+	return false
+}
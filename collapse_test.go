@@ -0,0 +1,73 @@
+package lscolors
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestCollapseWriter(t *testing.T) {
+	ls, err := ParseLSColors("di=01;34:*.log=32:*.tar=30;41:*.c=38;5;42")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sb strings.Builder
+	w := NewCollapseWriter(&sb, ls)
+	entries := []testDirEntry{
+		{name: "a.log"},
+		{name: "b.log"},
+		{name: "c.log"},
+		{name: "dir", mode: os.ModeDir},
+		{name: "file"},
+		{name: "x.tar"},
+		{name: "y.tar"},
+		{name: "z.c"},
+	}
+	for _, d := range entries {
+		if err := w.WriteEntry(d.name, d); err != nil {
+			t.Fatal(err)
+		}
+		if err := w.WriteSeparator("\n"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	want := "\x1b[32ma.log\nb.log\nc.log\n" +
+		"\x1b[0;01;34mdir\n" +
+		"\x1b[0mfile\n" +
+		// Backgrounds are reset before separators
+		"\x1b[30;41mx.tar\x1b[0m\n\x1b[30;41my.tar\x1b[0m\n" +
+		"\x1b[38;5;42mz.c\n\x1b[0m"
+	if got := sb.String(); got != want {
+		t.Errorf("got:  %q\nwant: %q", got, want)
+	}
+	if got := strings.Count(sb.String(), "\x1b[32m"); got != 1 {
+		t.Errorf("emitted the .log color %d times; want: 1", got)
+	}
+
+	// Flush is a no-op without an active color
+	sb.Reset()
+	if err := w.Flush(); err != nil || sb.Len() != 0 {
+		t.Errorf("Flush() wrote %q, %v; want nothing", sb.String(), err)
+	}
+}
+
+func TestHasBackground(t *testing.T) {
+	tests := map[string]bool{
+		"01;34":         false,
+		"30;41":         true,
+		"01;104":        true,
+		"48;5;1":        true,
+		"38;5;42":       false,
+		"38;5;44":       false, // 44 is part of the extended color
+		"38;2;40;41;42": false,
+		"38;2;1;2;3;44": true,
+	}
+	for seq, want := range tests {
+		if got := hasBackground(seq); got != want {
+			t.Errorf("hasBackground(%q) = %t; want: %t", seq, got, want)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package lscolors
+
+import (
+	"io"
+	"io/fs"
+	"unicode/utf8"
+)
+
+// A ColumnWriter writes colored text to an io.Writer while tracking the
+// visible column, in which wide East Asian characters occupy two cells
+// and combining characters none, so that text can be wrapped or truncated
+// to a fixed width without counting or splitting escape sequences. When a
+// line is wrapped the active color is reset before the newline and
+// restored after it so that colors never bleed into the margin.
+type ColumnWriter struct {
+	// Width is the number of columns at which lines are wrapped or
+	// truncated. Zero means no limit.
+	Width int
+
+	// Truncate discards text that extends past Width instead of wrapping
+	// it. Escape sequences are still written so that the color is reset
+	// at the end of a truncated entry.
+	Truncate bool
+
+	w   io.Writer
+	c   *LSColors
+	col int
+	sgr string // active SGR sequences, restored after wrapping
+	buf []byte
+}
+
+// NewColumnWriter returns a ColumnWriter that writes to w with a width of
+// width columns and colors entries with c.
+func NewColumnWriter(w io.Writer, c *LSColors, width int) *ColumnWriter {
+	return &ColumnWriter{Width: width, w: w, c: c}
+}
+
+// Column returns the visible column of the cursor, which is zero at the
+// start of a line.
+func (w *ColumnWriter) Column() int { return w.col }
+
+// Write writes p, which may contain escape sequences, wrapping or
+// truncating it at Width. Escape sequences should not be split across
+// calls.
+func (w *ColumnWriter) Write(p []byte) (int, error) {
+	return w.WriteString(string(p))
+}
+
+// WriteString is like Write but writes the contents of s.
+func (w *ColumnWriter) WriteString(s string) (int, error) {
+	b := w.buf[:0]
+	for i := 0; i < len(s); {
+		switch s[i] {
+		case '\x1b':
+			n := escapeLen(s[i:])
+			b = append(b, s[i:i+n]...)
+			w.trackSGR(s[i : i+n])
+			i += n
+			continue
+		case '\n':
+			b = append(b, '\n')
+			w.col = 0
+			i++
+			continue
+		}
+		r, n := utf8.DecodeRuneInString(s[i:])
+		rw := runeWidth(r)
+		// Wide characters that do not fit are moved to the next line,
+		// and zero width characters stay with the preceding character.
+		if w.Width > 0 && rw > 0 && w.col > 0 && w.col+rw > w.Width {
+			if w.Truncate {
+				i += n
+				continue
+			}
+			if w.sgr != "" {
+				b = append(b, resetSeq...)
+			}
+			b = append(b, '\n')
+			b = append(b, w.sgr...)
+			w.col = 0
+		}
+		b = append(b, s[i:i+n]...)
+		w.col += rw
+		i += n
+	}
+	w.buf = b
+	if _, err := w.w.Write(b); err != nil {
+		return 0, err
+	}
+	return len(s), nil
+}
+
+// WriteEntry writes the name of the entry d, found at path, colored by
+// MatchEntry.
+func (w *ColumnWriter) WriteEntry(path string, d fs.DirEntry) error {
+	_, err := w.WriteString(w.c.MatchEntry(path, d).Format(d.Name()))
+	return err
+}
+
+// trackSGR updates the active SGR sequences with the escape sequence seq.
+// Sequences that start with a reset replace the active sequences and other
+// SGR sequences are added to them.
+func (w *ColumnWriter) trackSGR(seq string) {
+	if len(seq) < 3 || seq[1] != '[' || seq[len(seq)-1] != 'm' {
+		return
+	}
+	params, ok := parseSGR(seq[2 : len(seq)-1])
+	switch {
+	case !ok:
+		return
+	case len(params) == 0 || params[0] == 0:
+		if len(params) > 1 {
+			w.sgr = seq
+		} else {
+			w.sgr = ""
+		}
+	default:
+		w.sgr += seq
+	}
+}
@@ -0,0 +1,96 @@
+package lscolors
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestVisibleLen(t *testing.T) {
+	for _, s := range []string{
+		"",
+		"plain",
+		"\x1b[01;34mdir\x1b[0m",
+		"\x1b[01;34m日本語\x1b[0m",
+		"a\x1b[38;2;1;2;3mb\x1b[Kc",
+		"\x1b]8;;http://x\x1b\\link\x1b]8;;\a",
+		"trailing\x1b[1",
+	} {
+		if got, want := VisibleLen(s), len([]rune(Strip(s))); got != want {
+			t.Errorf("VisibleLen(%q) = %d; want: %d", s, got, want)
+		}
+	}
+}
+
+func TestColumnWriter(t *testing.T) {
+	ls, err := ParseLSColors("di=01;34:*.go=32")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	w := NewColumnWriter(&buf, ls, 4)
+	if _, err := w.WriteString("ab"); err != nil {
+		t.Fatal(err)
+	}
+	// Wraps mid-entry: the color is reset before the newline and restored
+	// after it.
+	if err := w.WriteEntry("main.go", testDirEntry{name: "main.go"}); err != nil {
+		t.Fatal(err)
+	}
+	want := "ab\x1b[32mma\x1b[0m\n\x1b[32min.g\x1b[0m\n\x1b[32mo\x1b[0m"
+	if got := buf.String(); got != want {
+		t.Errorf("got:  %q\nwant: %q", got, want)
+	}
+	if got := w.Column(); got != 1 {
+		t.Errorf("Column() = %d; want: %d", got, 1)
+	}
+	if got, want := Strip(buf.String()), "abma\nin.g\no"; got != want {
+		t.Errorf("Strip = %q; want: %q", got, want)
+	}
+
+	// Newlines reset the column and the reset at the end of the entry
+	// clears the active color.
+	buf.Reset()
+	if _, err := w.WriteString("\nabcdef"); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := buf.String(), "\nabcd\nef"; got != want {
+		t.Errorf("got:  %q\nwant: %q", got, want)
+	}
+
+	// Wide characters occupy two cells and are wrapped whole.
+	buf.Reset()
+	if _, err := w.WriteString("\na日本語e\u0301"); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := buf.String(), "\na日\n本語\ne\u0301"; got != want {
+		t.Errorf("got:  %q\nwant: %q", got, want)
+	}
+	if got := w.Column(); got != 1 {
+		t.Errorf("Column() = %d; want: %d", got, 1)
+	}
+}
+
+func TestColumnWriterTruncate(t *testing.T) {
+	ls, err := ParseLSColors("di=01;34:*.go=32")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	w := NewColumnWriter(&buf, ls, 4)
+	w.Truncate = true
+	if err := w.WriteEntry("main.go", testDirEntry{name: "main.go"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write([]byte("\n日本語です")); err != nil {
+		t.Fatal(err)
+	}
+	// The reset at the end of the truncated entry is preserved and wide
+	// characters occupy two columns.
+	want := "\x1b[32mmain\x1b[0m\n日本"
+	if got := buf.String(); got != want {
+		t.Errorf("got:  %q\nwant: %q", got, want)
+	}
+	if got := w.Column(); got != 4 {
+		t.Errorf("Column() = %d; want: %d", got, 4)
+	}
+}
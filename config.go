@@ -0,0 +1,60 @@
+package lscolors
+
+import (
+	"bytes"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// UserConfigPath returns the path of the per-user configuration file read
+// by LoadUserConfig: $XDG_CONFIG_HOME/lscolors/config or, if
+// XDG_CONFIG_HOME is not set to an absolute path, ~/.config/lscolors/config.
+func UserConfigPath() (string, error) {
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if !filepath.IsAbs(dir) {
+		// The XDG spec requires relative paths to be ignored.
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		dir = filepath.Join(home, ".config")
+	}
+	return filepath.Join(dir, "lscolors", "config"), nil
+}
+
+// LoadUserConfig returns the user's preferred colors. The file returned by
+// UserConfigPath is used if it exists and may contain either an LS_COLORS
+// value or a dircolors database. Otherwise the colors are read from the
+// environment, as by NewLSColorsAuto, and if neither LS_COLORS nor
+// LSCOLORS is set the GNU ls defaults (DefaultLSColors) are returned.
+func LoadUserConfig() (*LSColors, error) {
+	name, err := UserConfigPath()
+	if err == nil {
+		ls, err := loadConfig(name)
+		if !errors.Is(err, fs.ErrNotExist) {
+			return ls, err
+		}
+	}
+	ls, err := NewLSColorsAuto()
+	if errors.Is(err, ErrNotSet) {
+		return DefaultLSColors(), nil
+	}
+	return ls, err
+}
+
+// loadConfig parses the configuration file name. A file consisting of a
+// single word that contains '=' is parsed as an LS_COLORS value, all other
+// files are parsed as a dircolors database.
+func loadConfig(name string) (*LSColors, error) {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	if s := bytes.TrimSpace(data); bytes.IndexByte(s, '=') >= 0 &&
+		bytes.IndexAny(s, " \t\r\n") < 0 {
+		return ParseLSColorsBytes(s)
+	}
+	return ParseDircolors(bytes.NewReader(data))
+}
@@ -0,0 +1,81 @@
+package lscolors
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeUserConfig(t *testing.T, data string) {
+	t.Helper()
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	if data == "" {
+		return
+	}
+	if err := os.MkdirAll(filepath.Join(dir, "lscolors"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "lscolors", "config"), []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// unsetenv unsets key for the duration of the test.
+func unsetenv(t *testing.T, key string) {
+	t.Setenv(key, "") // restores the original value
+	os.Unsetenv(key)
+}
+
+func TestUserConfigPath(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", "/xdg")
+	if got, err := UserConfigPath(); err != nil || got != "/xdg/lscolors/config" {
+		t.Errorf("UserConfigPath() = %q, %v; want: %q", got, err, "/xdg/lscolors/config")
+	}
+	t.Setenv("HOME", "/home/u")
+	for _, dir := range []string{"", "relative"} {
+		t.Setenv("XDG_CONFIG_HOME", dir)
+		if got, err := UserConfigPath(); err != nil || got != "/home/u/.config/lscolors/config" {
+			t.Errorf("UserConfigPath() = %q, %v; want: %q", got, err, "/home/u/.config/lscolors/config")
+		}
+	}
+}
+
+func TestLoadUserConfig(t *testing.T) {
+	t.Setenv("LS_COLORS", "di=32")
+	unsetenv(t, "LSCOLORS")
+
+	tests := []struct {
+		config string
+		di     string
+	}{
+		{"di=01;35:*.go=33\n", "01;35"},             // LS_COLORS value
+		{"# comment\nDIR 01;31\n.go 33\n", "01;31"}, // dircolors database
+		{"", "32"}, // no config: LS_COLORS
+	}
+	for _, x := range tests {
+		writeUserConfig(t, x.config)
+		ls, err := LoadUserConfig()
+		if err != nil {
+			t.Fatalf("%q: %v", x.config, err)
+		}
+		if ls.DI.Seq != x.di {
+			t.Errorf("%q: DI = %q; want: %q", x.config, ls.DI.Seq, x.di)
+		}
+	}
+
+	writeUserConfig(t, "")
+	unsetenv(t, "LS_COLORS")
+	ls, err := LoadUserConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !equalColors(ls, DefaultLSColors()) {
+		t.Errorf("LoadUserConfig() = %q; want the defaults: %q", ls, DefaultLSColors())
+	}
+
+	writeUserConfig(t, "di=01;34:bad")
+	if _, err := LoadUserConfig(); err == nil {
+		t.Error("LoadUserConfig: expected error for invalid config")
+	}
+}
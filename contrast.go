@@ -0,0 +1,127 @@
+package lscolors
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// minContrast is the minimum contrast ratio between a foreground and its
+// background accepted by EnsureContrast. It is the WCAG 2 minimum for user
+// interface components and large text.
+const minContrast = 3.0
+
+// sgrColor returns the RGB value of the last foreground, or if bg is true
+// background, color set by the SGR parameters params.
+func sgrColor(params []int, bg bool) (rgb [3]uint8, ok bool) {
+	base := 30
+	if bg {
+		base = 40
+	}
+	for i := 0; i < len(params); i++ {
+		switch p := params[i]; {
+		case base <= p && p <= base+7:
+			rgb, ok = ansi16[p-base], true
+		case base+60 <= p && p <= base+67:
+			rgb, ok = ansi16[p-base-60+8], true
+		case p == base+9:
+			ok = false // default color
+		case p == 38 || p == 48:
+			switch {
+			case i+2 < len(params) && params[i+1] == 5:
+				if p == base+8 {
+					rgb, ok = color256ToRGB(params[i+2]), true
+				}
+				i += 2
+			case i+4 < len(params) && params[i+1] == 2:
+				if p == base+8 {
+					rgb = [3]uint8{uint8(params[i+2]), uint8(params[i+3]), uint8(params[i+4])}
+					ok = true
+				}
+				i += 4
+			default:
+				return rgb, ok // malformed: ignore the rest
+			}
+		case p == 0:
+			ok = false
+		}
+	}
+	return rgb, ok
+}
+
+// luminance returns the WCAG 2 relative luminance of rgb.
+func luminance(rgb [3]uint8) float64 {
+	var l [3]float64
+	for i, v := range rgb {
+		c := float64(v) / 255
+		if c <= 0.03928 {
+			l[i] = c / 12.92
+		} else {
+			l[i] = math.Pow((c+0.055)/1.055, 2.4)
+		}
+	}
+	return 0.2126*l[0] + 0.7152*l[1] + 0.0722*l[2]
+}
+
+// contrastRatio returns the WCAG 2 contrast ratio of a and b, which ranges
+// from 1 to 21.
+func contrastRatio(a, b [3]uint8) float64 {
+	la, lb := luminance(a), luminance(b)
+	if la < lb {
+		la, lb = lb, la
+	}
+	return (la + 0.05) / (lb + 0.05)
+}
+
+// EnsureContrast returns the sequence of c with its foreground replaced by
+// bright white ("97") or black ("30"), whichever is more readable, if the
+// foreground would be hard to read on the background bg, which is the SGR
+// sequence of the background such as "40", "48;5;236" or "48;2;0;0;32".
+// Colors are compared by their WCAG contrast ratio using xterm's values for
+// the 16 standard colors. The sequence is returned unmodified if it sets
+// its own background, or if either color is not known.
+func (c *ColorExtension) EnsureContrast(bg string) string {
+	params, ok := parseSGR(c.Seq)
+	if !ok {
+		return c.Seq
+	}
+	if _, ok := sgrColor(params, true); ok {
+		return c.Seq
+	}
+	fg, ok := sgrColor(params, false)
+	if !ok {
+		return c.Seq
+	}
+	bgParams, ok := parseSGR(bg)
+	if !ok {
+		return c.Seq
+	}
+	bgc, ok := sgrColor(bgParams, true)
+	if !ok || contrastRatio(fg, bgc) >= minContrast {
+		return c.Seq
+	}
+	repl := "97"
+	if contrastRatio(ansi16[0], bgc) > contrastRatio(ansi16[15], bgc) {
+		repl = "30"
+	}
+	// Remove the foreground colors, including extended ones, and append
+	// the replacement.
+	parts := strings.Split(c.Seq, ";")
+	out := make([]string, 0, len(parts)+1)
+	for i := 0; i < len(parts); i++ {
+		p, _ := strconv.Atoi(parts[i])
+		switch {
+		case 30 <= p && p <= 37, 90 <= p && p <= 97, p == 39:
+			continue
+		case p == 38 && i+1 < len(parts) && parts[i+1] == "5":
+			i += 2
+			continue
+		case p == 38 && i+1 < len(parts) && parts[i+1] == "2":
+			i += 4
+			continue
+		}
+		out = append(out, parts[i])
+	}
+	out = append(out, repl)
+	return strings.Join(out, ";")
+}
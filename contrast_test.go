@@ -0,0 +1,38 @@
+package lscolors
+
+import "testing"
+
+func TestEnsureContrast(t *testing.T) {
+	tests := []struct {
+		seq, bg, want string
+	}{
+		{"34", "40", "97"},           // blue on black
+		{"01;30", "40", "01;97"},     // black on black
+		{"38;5;17", "48;5;16", "97"}, // dark blue on black
+		{"04;38;2;20;20;20", "48;2;0;0;0", "04;97"},
+		{"93", "107", "30"},      // yellow on white
+		{"01;34", "47", "01;34"}, // readable
+		{"01;32", "40", "01;32"}, // readable
+		{"30;43", "40", "30;43"}, // sets its own background
+		{"01", "40", "01"},       // default foreground
+		{"34", "", "34"},         // default background
+		{"34", "bad", "34"},
+		{"", "40", ""},
+	}
+	for _, x := range tests {
+		e := ColorExtension{Seq: x.seq}
+		if got := e.EnsureContrast(x.bg); got != x.want {
+			t.Errorf("EnsureContrast(%q, %q) = %q; want: %q", x.seq, x.bg, got, x.want)
+		}
+	}
+}
+
+func TestContrastRatio(t *testing.T) {
+	black, white := [3]uint8{}, [3]uint8{255, 255, 255}
+	if r := contrastRatio(black, white); r < 20.99 || r > 21.01 {
+		t.Errorf("contrastRatio(black, white) = %f; want: 21", r)
+	}
+	if r := contrastRatio(white, white); r != 1 {
+		t.Errorf("contrastRatio(white, white) = %f; want: 1", r)
+	}
+}
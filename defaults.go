@@ -0,0 +1,51 @@
+package lscolors
+
+// The default color sequences used by GNU ls when LS_COLORS does not set an
+// indicator (the color_indicator table in coreutils/ls.c). Indicators not
+// listed here, such as "fi" and "or", are uncolored by default.
+const (
+	DefaultResetSeq               = "0"     // rs
+	DefaultDirSeq                 = "01;34" // di: bright blue
+	DefaultLinkSeq                = "01;36" // ln: bright cyan
+	DefaultPipeSeq                = "33"    // pi: yellow
+	DefaultSocketSeq              = "01;35" // so: bright magenta
+	DefaultBlockDeviceSeq         = "01;33" // bd: bright yellow
+	DefaultCharDeviceSeq          = "01;33" // cd: bright yellow
+	DefaultExecutableSeq          = "01;32" // ex: bright green
+	DefaultDoorSeq                = "01;35" // do: bright magenta
+	DefaultSetuidSeq              = "37;41" // su: white on red
+	DefaultSetgidSeq              = "30;43" // sg: black on yellow
+	DefaultStickySeq              = "37;44" // st: white on blue
+	DefaultOtherWritableSeq       = "34;42" // ow: blue on green
+	DefaultStickyOtherWritableSeq = "30;42" // tw: black on green
+)
+
+// DefaultLSColors returns the colors GNU ls uses when LS_COLORS is not set.
+// It has no extension rules.
+func DefaultLSColors() *LSColors {
+	return &LSColors{
+		DI:        ColorExtension{Ext: "di", Seq: DefaultDirSeq},
+		LN:        ColorExtension{Ext: "ln", Seq: DefaultLinkSeq},
+		PI:        ColorExtension{Ext: "pi", Seq: DefaultPipeSeq},
+		SO:        ColorExtension{Ext: "so", Seq: DefaultSocketSeq},
+		BD:        ColorExtension{Ext: "bd", Seq: DefaultBlockDeviceSeq},
+		CD:        ColorExtension{Ext: "cd", Seq: DefaultCharDeviceSeq},
+		EX:        ColorExtension{Ext: "ex", Seq: DefaultExecutableSeq},
+		TW:        ColorExtension{Ext: "tw", Seq: DefaultStickyOtherWritableSeq},
+		ST:        ColorExtension{Ext: "st", Seq: DefaultStickySeq},
+		OW:        ColorExtension{Ext: "ow", Seq: DefaultOtherWritableSeq},
+		RS:        ColorExtension{Ext: "rs", Seq: DefaultResetSeq},
+		finalized: true,
+	}
+}
+
+// MinimalLSColors returns a palette that only colors directories and
+// symbolic links, using the GNU ls defaults, for tools that want light
+// coloring without parsing LS_COLORS.
+func MinimalLSColors() *LSColors {
+	return &LSColors{
+		DI:        ColorExtension{Ext: "di", Seq: DefaultDirSeq},
+		LN:        ColorExtension{Ext: "ln", Seq: DefaultLinkSeq},
+		finalized: true,
+	}
+}
@@ -0,0 +1,78 @@
+package lscolors
+
+import "testing"
+
+func TestDefaultSeqs(t *testing.T) {
+	// From the color_indicator table in coreutils/ls.c
+	coreutils := map[string]string{
+		"rs": "0",
+		"di": "01;34",
+		"ln": "01;36",
+		"pi": "33",
+		"so": "01;35",
+		"bd": "01;33",
+		"cd": "01;33",
+		"ex": "01;32",
+		"do": "01;35",
+		"su": "37;41",
+		"sg": "30;43",
+		"st": "37;44",
+		"ow": "34;42",
+		"tw": "30;42",
+	}
+	consts := map[string]string{
+		"rs": DefaultResetSeq,
+		"di": DefaultDirSeq,
+		"ln": DefaultLinkSeq,
+		"pi": DefaultPipeSeq,
+		"so": DefaultSocketSeq,
+		"bd": DefaultBlockDeviceSeq,
+		"cd": DefaultCharDeviceSeq,
+		"ex": DefaultExecutableSeq,
+		"do": DefaultDoorSeq,
+		"su": DefaultSetuidSeq,
+		"sg": DefaultSetgidSeq,
+		"st": DefaultStickySeq,
+		"ow": DefaultOtherWritableSeq,
+		"tw": DefaultStickyOtherWritableSeq,
+	}
+	for key, want := range coreutils {
+		if got := consts[key]; got != want {
+			t.Errorf("%s: default = %q; want: %q", key, got, want)
+		}
+	}
+
+	ls := DefaultLSColors()
+	if err := ls.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	n := 0
+	ls.Range(func(key, seq string) bool {
+		if want := coreutils[key]; seq != want {
+			t.Errorf("DefaultLSColors: %s = %q; want: %q", key, seq, want)
+		}
+		n++
+		return true
+	})
+	// All of the coreutils defaults except for "do", "su" and "sg", which
+	// LSColors does not support.
+	if want := len(coreutils) - 3; n != want {
+		t.Errorf("DefaultLSColors: got %d rules; want: %d", n, want)
+	}
+}
+
+func TestMinimalLSColors(t *testing.T) {
+	ls := MinimalLSColors()
+	if got, want := ls.String(), "di="+DefaultDirSeq+":ln="+DefaultLinkSeq; got != want {
+		t.Errorf("String() = %q; want: %q", got, want)
+	}
+	if got := ls.Entries(); len(got) != 2 {
+		t.Errorf("Entries() = %+v; want only di and ln", got)
+	}
+	if e := ls.MatchEntry("run", testDirEntry{name: "run", mode: 0755}); e != &NoColor {
+		t.Errorf("MatchEntry(executable) = %+v; want: NoColor", e)
+	}
+	if err := ls.Validate(); err != nil {
+		t.Error(err)
+	}
+}
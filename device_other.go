@@ -0,0 +1,11 @@
+//go:build !unix
+
+package lscolors
+
+import "io/fs"
+
+// deviceMajor returns the major device number of the device fi. Device
+// numbers are not supported on this platform.
+func deviceMajor(fi fs.FileInfo) (uint32, bool) {
+	return 0, false
+}
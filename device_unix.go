@@ -0,0 +1,41 @@
+//go:build unix
+
+package lscolors
+
+import (
+	"io/fs"
+	"runtime"
+	"syscall"
+)
+
+// deviceMajor returns the major device number of the device fi. The second
+// result is false if it is unknown.
+func deviceMajor(fi fs.FileInfo) (uint32, bool) {
+	st, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, false
+	}
+	return major(uint64(st.Rdev))
+}
+
+// major extracts the major number from the device number dev, which is
+// encoded differently by each OS (see makedev(3)).
+func major(dev uint64) (uint32, bool) {
+	switch runtime.GOOS {
+	case "linux", "android":
+		return uint32((dev>>8)&0xfff | (dev>>32)&^0xfff), true
+	case "darwin", "ios":
+		return uint32(dev>>24) & 0xff, true
+	case "freebsd":
+		return uint32((dev>>32)&0xffffff00 | (dev>>8)&0xff), true
+	case "dragonfly":
+		return uint32(dev>>8) & 0xff, true
+	case "netbsd":
+		return uint32(dev&0xfff00) >> 8, true
+	case "openbsd":
+		return uint32(dev&0xff00) >> 8, true
+	case "solaris", "illumos":
+		return uint32(dev >> 32), true
+	}
+	return 0, false
+}
@@ -0,0 +1,91 @@
+//go:build unix
+
+package lscolors
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestMajor(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("device number encoding is only tested on Linux")
+	}
+	tests := []struct {
+		dev  uint64
+		want uint32
+	}{
+		{1<<8 | 3, 1},     // /dev/null
+		{136<<8 | 2, 136}, // /dev/pts/2
+		{259<<8 | 1, 259}, // nvme0n1p1
+		{0x123<<8 | 0xab, 0x123},
+		{0x1000<<32 | 0x123<<8, 0x1123}, // large major
+	}
+	for _, x := range tests {
+		if got, ok := major(x.dev); !ok || got != x.want {
+			t.Errorf("major(%#x) = %d, %t; want: %d, true", x.dev, got, ok, x.want)
+		}
+	}
+}
+
+// findDevice returns the FileInfo of the first device in /dev with the
+// given type.
+func findDevice(t *testing.T, block bool) (string, fs.FileInfo) {
+	names := []string{"/dev/null", "/dev/zero", "/dev/tty"}
+	if block {
+		names, _ = filepath.Glob("/dev/*")
+	}
+	for _, name := range names {
+		fi, err := os.Lstat(name)
+		if err != nil || fi.Mode()&fs.ModeDevice == 0 {
+			continue
+		}
+		if (fi.Mode()&fs.ModeCharDevice == 0) == block {
+			if _, ok := deviceMajor(fi); ok {
+				return name, fi
+			}
+		}
+	}
+	t.Skipf("no device found (block: %t)", block)
+	return "", nil
+}
+
+func TestDeviceRules(t *testing.T) {
+	ls, err := ParseLSColors("bd=01;33:cd=01;33")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, block := range []bool{false, true} {
+		t.Run(map[bool]string{false: "Char", true: "Block"}[block], func(t *testing.T) {
+			name, fi := findDevice(t, block)
+			major, _ := deviceMajor(fi)
+			want := &ls.CD
+			if block {
+				want = &ls.BD
+			}
+			ls.DeviceRules = nil
+			if e := ls.MatchInfo(name, fi); e != want {
+				t.Errorf("MatchInfo(%q) = %+v; want: %+v", name, e, want)
+			}
+
+			// The major number and type must both match
+			ls.DeviceRules = []DeviceRule{
+				{Major: major + 1, Block: block, ColorExtension: ColorExtension{Seq: "31"}},
+				{Major: major, Block: !block, ColorExtension: ColorExtension{Seq: "32"}},
+				{Major: major, Block: block, ColorExtension: ColorExtension{Seq: "35"}},
+			}
+			if e := ls.MatchInfo(name, fi); e != &ls.DeviceRules[2].ColorExtension {
+				t.Errorf("MatchInfo(%q) = %+v; want: %q", name, e, "35")
+			}
+
+			// Not used by MatchEntry, which has no device number
+			d := fs.FileInfoToDirEntry(fi)
+			if e := ls.MatchEntry(name, d); e != want {
+				t.Errorf("MatchEntry(%q) = %+v; want: %+v", name, e, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package lscolors
+
+// A PaletteChange is a rule whose sequence differs between two palettes.
+// Old is empty if the rule was added and New is empty if it was removed.
+type PaletteChange struct {
+	Key string // rule key, as passed to Range: "di", "*.go" or ".git*"
+	Old string
+	New string
+}
+
+// Diff returns the rules whose sequence differs between c and to: first
+// the rules of to that were added or changed, in the order used by Range,
+// and then the rules of c that were removed. The result is empty if both
+// palettes serialize to the same rules.
+func (c *LSColors) Diff(to *LSColors) []PaletteChange {
+	old := make(map[string]string)
+	c.Range(func(key, seq string) bool {
+		old[key] = seq
+		return true
+	})
+	var changes []PaletteChange
+	seen := make(map[string]bool)
+	to.Range(func(key, seq string) bool {
+		if seen[key] {
+			return true
+		}
+		seen[key] = true
+		if prev := old[key]; prev != seq {
+			changes = append(changes, PaletteChange{Key: key, Old: prev, New: seq})
+		}
+		return true
+	})
+	c.Range(func(key, seq string) bool {
+		if !seen[key] {
+			seen[key] = true
+			changes = append(changes, PaletteChange{Key: key, Old: old[key]})
+		}
+		return true
+	})
+	return changes
+}
+
+// ReparseFrom parses the LS_COLORS value s and replaces the indicators,
+// escape codes, extensions and globs of c with it, returning the rules
+// that changed (see Diff). It lets programs that watch LS_COLORS or a
+// configuration file reload it and re-render only what changed. Options
+// that are not part of LS_COLORS, such as SizeRules or FoldCase, are kept,
+// as are Names that do not come from an extension rule.
+//
+// If s cannot be parsed, or contains invalid entries, c is not modified
+// and the error is returned. ReparseFrom modifies c in place so it must
+// not be used on a palette held by a PaletteHolder: reparse a Clone and
+// Store it instead.
+func (c *LSColors) ReparseFrom(s string) ([]PaletteChange, error) {
+	ls, err := ParseLSColors(s)
+	if err != nil {
+		return nil, err
+	}
+	changes := c.Diff(ls)
+
+	// Keep the names that were added directly and not by an extension.
+	for name, e := range c.Names {
+		if !c.hasExt(name) {
+			if ls.Names == nil {
+				ls.Names = make(map[string]*ColorExtension)
+			}
+			ls.Names[name] = e
+		}
+	}
+	for i, e := range ls.indicators() {
+		*c.indicators()[i] = *e
+	}
+	c.LeftCode, c.RightCode, c.EndCode = ls.LeftCode, ls.RightCode, ls.EndCode
+	c.Exts = ls.Exts
+	c.Globs = ls.Globs
+	c.Names = ls.Names
+	c.invalid = nil
+	c.extOrder = ls.extOrder
+	c.shadowed = ls.shadowed
+	c.finalized = ls.finalized
+	return changes, nil
+}
+
+// hasExt reports if c has an extension rule for ext.
+func (c *LSColors) hasExt(ext string) bool {
+	for _, e := range c.Exts {
+		if e.Ext == ext {
+			return true
+		}
+	}
+	return false
+}
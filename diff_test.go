@@ -0,0 +1,111 @@
+package lscolors
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	a, err := ParseLSColors("di=01;34:ln=01;36:*.go=32:*.c=33:.git*=01")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := ParseLSColors("di=01;35:ln=01;36:*.go=32:*.rs=31:.git*=02")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []PaletteChange{
+		{Key: "di", Old: "01;34", New: "01;35"},
+		{Key: "*.rs", New: "31"},
+		{Key: ".git*", Old: "01", New: "02"},
+		{Key: "*.c", Old: "33"},
+	}
+	if got := a.Diff(b); !reflect.DeepEqual(got, want) {
+		t.Errorf("Diff:\ngot:  %+v\nwant: %+v", got, want)
+	}
+	if got := a.Diff(a.Clone()); len(got) != 0 {
+		t.Errorf("Diff of equal palettes = %+v; want: none", got)
+	}
+}
+
+func TestReparseFrom(t *testing.T) {
+	ls, err := ParseLSColors("di=01;34:*.go=32:*Makefile=33")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ls.FoldCase = true
+	ls.Names["Dockerfile"] = &ColorExtension{Ext: "Dockerfile", Seq: "36"}
+
+	changes, err := ls.ReparseFrom("di=01;35:*.go=32:*.rs=31")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []PaletteChange{
+		{Key: "di", Old: "01;34", New: "01;35"},
+		{Key: "*.rs", New: "31"},
+		{Key: "*Makefile", Old: "33"},
+	}
+	if !reflect.DeepEqual(changes, want) {
+		t.Errorf("ReparseFrom:\ngot:  %+v\nwant: %+v", changes, want)
+	}
+	if got := ls.String(); got != "di=01;35:*.go=32:*.rs=31" {
+		t.Errorf("String() = %q", got)
+	}
+	if !ls.FoldCase {
+		t.Error("ReparseFrom reset FoldCase")
+	}
+	if _, ok := ls.Names["Makefile"]; ok {
+		t.Error("ReparseFrom kept the name of a removed extension")
+	}
+	if e := ls.Names["Dockerfile"]; e == nil || e.Seq != "36" {
+		t.Errorf("ReparseFrom dropped a name: %+v", ls.Names)
+	}
+	if e := ls.MatchName("a.rs", 0); e.Seq != "31" {
+		t.Errorf("MatchName(%q) = %q; want: %q", "a.rs", e.Seq, "31")
+	}
+}
+
+func TestReparseFromCodes(t *testing.T) {
+	ls, err := ParseLSColors(`di=01;34:lc=\e[:rc=m:ec=\e[0m`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ls.ReparseFrom(`di=01;34:rc=M`); err != nil {
+		t.Fatal(err)
+	}
+	if ls.LeftCode != "" || ls.RightCode != "M" || ls.EndCode != "" {
+		t.Errorf("ReparseFrom: codes = %q, %q, %q; want: %q, %q, %q",
+			ls.LeftCode, ls.RightCode, ls.EndCode, "", "M", "")
+	}
+	if got := ls.Reset(); got != "\x1b[0M" {
+		t.Errorf("Reset() = %q; want: %q", got, "\x1b[0M")
+	}
+}
+
+func TestReparseFromError(t *testing.T) {
+	const colors = "di=01;34:*.go=32"
+	for _, s := range []string{"", "di=01;35:*.go=bad", "di=01;35:junk"} {
+		ls, err := ParseLSColors(colors)
+		if err != nil {
+			t.Fatal(err)
+		}
+		changes, err := ls.ReparseFrom(s)
+		if err == nil {
+			t.Errorf("ReparseFrom(%q): expected error", s)
+		}
+		if changes != nil {
+			t.Errorf("ReparseFrom(%q) = %+v; want: nil", s, changes)
+		}
+		if got := ls.String(); got != colors {
+			t.Errorf("ReparseFrom(%q) modified the palette: %q", s, got)
+		}
+		if len(ls.Invalid()) != 0 {
+			t.Errorf("ReparseFrom(%q) set invalid entries: %q", s, ls.Invalid())
+		}
+	}
+	ls := &LSColors{}
+	if _, err := ls.ReparseFrom(""); !errors.Is(err, ErrEmpty) {
+		t.Errorf("ReparseFrom(%q) = %v; want: %v", "", err, ErrEmpty)
+	}
+}
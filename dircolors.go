@@ -0,0 +1,287 @@
+package lscolors
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+)
+
+// dircolorsKeys maps dircolors database keywords to LS_COLORS keys.
+var dircolorsKeys = map[string]string{
+	"NORMAL":                "no",
+	"NORM":                  "no",
+	"FILE":                  "fi",
+	"RESET":                 "rs",
+	"DIR":                   "di",
+	"LNK":                   "ln",
+	"LINK":                  "ln",
+	"SYMLINK":               "ln",
+	"ORPHAN":                "or",
+	"MISSING":               "mi",
+	"FIFO":                  "pi",
+	"PIPE":                  "pi",
+	"SOCK":                  "so",
+	"BLK":                   "bd",
+	"BLOCK":                 "bd",
+	"CHR":                   "cd",
+	"CHAR":                  "cd",
+	"EXEC":                  "ex",
+	"STICKY":                "st",
+	"OTHER_WRITABLE":        "ow",
+	"OWR":                   "ow",
+	"STICKY_OTHER_WRITABLE": "tw",
+	"OWT":                   "tw",
+}
+
+// dircolorsState tracks whether the entries being parsed apply to the
+// current terminal. It mirrors the state machine used by GNU dircolors.
+type dircolorsState int
+
+const (
+	stateGlobal   dircolorsState = iota // no TERM lines seen: entries apply
+	stateTermNo                         // the current TERM group did not match
+	stateTermYes                        // the current TERM group matched
+	stateTermSure                       // a TERM line in the current group matched
+)
+
+// ParseDircolors parses a dircolors database, the format used by
+// dircolors(1) and ~/.dir_colors, using the TERM and COLORTERM environment
+// variables to select the entries that apply.
+func ParseDircolors(r io.Reader) (*LSColors, error) {
+	return ParseDircolorsTerm(r, os.Getenv("TERM"), os.Getenv("COLORTERM"))
+}
+
+// ParseDircolorsTerm is like ParseDircolors but selects entries using the
+// provided term and colorterm values instead of the environment.
+//
+// Consecutive TERM and COLORTERM lines form a group and the entries that
+// follow a group only apply if one of its patterns, which use path.Match
+// syntax, matches. Entries before the first group always apply.
+//
+// The LEFTCODE, RIGHTCODE and ENDCODE values may contain the escapes
+// understood by GNU ls, such as "\e", "\033", "\x1b" and "^[", and are
+// decoded. Keywords that have no equivalent in LSColors (such as DOOR) are
+// ignored.
+func ParseDircolorsTerm(r io.Reader, term, colorterm string) (*LSColors, error) {
+	var ls LSColors
+	var invalid []string
+	state := stateGlobal
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		keyword, arg, ok := parseDircolorsLine(sc.Text())
+		if !ok {
+			continue
+		}
+		switch strings.ToUpper(keyword) {
+		case "TERM", "COLORTERM":
+			val := term
+			if strings.EqualFold(keyword, "COLORTERM") {
+				val = colorterm
+			}
+			if state != stateTermSure {
+				if ok, _ := path.Match(arg, val); ok {
+					state = stateTermSure
+				} else {
+					state = stateTermNo
+				}
+			}
+			continue
+		}
+		if state == stateTermSure {
+			state = stateTermYes
+		}
+		if state == stateTermNo {
+			continue
+		}
+
+		var code *string
+		switch strings.ToUpper(keyword) {
+		case "LEFTCODE", "LEFT":
+			code = &ls.LeftCode
+		case "RIGHTCODE", "RIGHT":
+			code = &ls.RightCode
+		case "ENDCODE", "END":
+			code = &ls.EndCode
+		}
+		if code != nil {
+			if v, ok := decodeEscapes(arg); ok {
+				*code = v
+			} else {
+				invalid = append(invalid, keyword+" "+arg)
+			}
+			continue
+		}
+
+		var key string
+		switch {
+		case keyword[0] == '.':
+			key = "*" + keyword
+		case keyword[0] == '*':
+			key = keyword
+		default:
+			key = dircolorsKeys[strings.ToUpper(keyword)]
+			if key == "" {
+				continue // unsupported keyword
+			}
+		}
+		if err := ls.Set(key, arg); err != nil {
+			invalid = append(invalid, keyword+" "+arg)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	ls.Finalize()
+	if len(invalid) > 0 {
+		ls.invalid = invalid
+		return &ls, fmt.Errorf("lscolors: invalid dircolors entries: %q", invalid)
+	}
+	return &ls, nil
+}
+
+// decodeEscapes decodes the backslash and caret escapes of a dircolors or
+// LS_COLORS value like GNU ls: C style escapes ("\e", "\n", etc.), octal
+// ("\033") and hex ("\x1b") escapes, "\_" for a space and caret notation
+// ("^[" for ESC and "^?" for DEL). It returns false if s contains an
+// invalid escape.
+func decodeEscapes(s string) (string, bool) {
+	if !strings.ContainsAny(s, `\^`) {
+		return s, true
+	}
+	b := make([]byte, 0, len(s))
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch c {
+		case '\\':
+			i++
+			if i == len(s) {
+				return "", false
+			}
+			c = s[i]
+			switch {
+			case '0' <= c && c <= '7':
+				n := 0
+				for j := 0; j < 3 && i < len(s) && '0' <= s[i] && s[i] <= '7'; j++ {
+					n = n*8 + int(s[i]-'0')
+					i++
+				}
+				i--
+				b = append(b, byte(n))
+			case c == 'x' || c == 'X':
+				n, j := 0, i+1
+				for ; j < len(s) && j < i+3 && isHex(s[j]); j++ {
+					n = n*16 + hexVal(s[j])
+				}
+				if j == i+1 {
+					return "", false
+				}
+				b = append(b, byte(n))
+				i = j - 1
+			default:
+				e, ok := cEscapes[c]
+				if !ok {
+					e = c // unknown escapes are literal
+				}
+				b = append(b, e)
+			}
+		case '^':
+			i++
+			if i == len(s) {
+				return "", false
+			}
+			c = s[i]
+			switch {
+			case c == '?':
+				b = append(b, 0x7f)
+			case '@' <= c && c <= '~':
+				b = append(b, c&0x1f)
+			default:
+				return "", false
+			}
+		default:
+			b = append(b, c)
+		}
+	}
+	return string(b), true
+}
+
+var cEscapes = map[byte]byte{
+	'a': '\a', 'b': '\b', 'e': 0x1b, 'f': '\f', 'n': '\n',
+	'r': '\r', 't': '\t', 'v': '\v', '?': 0x7f, '_': ' ',
+}
+
+func isHex(c byte) bool {
+	return isDigit(c) || 'a' <= c|0x20 && c|0x20 <= 'f'
+}
+
+func hexVal(c byte) int {
+	if isDigit(c) {
+		return int(c - '0')
+	}
+	return int(c|0x20-'a') + 10
+}
+
+// parseDircolorsLine returns the keyword and argument of a dircolors line.
+// It returns false for blank lines and comments. Comments start with a '#'
+// at the start of a line or after whitespace.
+func parseDircolorsLine(line string) (keyword, arg string, ok bool) {
+	for i := 0; i < len(line); i++ {
+		if line[i] == '#' && (i == 0 || line[i-1] == ' ' || line[i-1] == '\t') {
+			line = line[:i]
+			break
+		}
+	}
+	line = strings.TrimSpace(line)
+	if i := strings.IndexAny(line, " \t"); i >= 0 {
+		keyword, arg = line[:i], strings.TrimSpace(line[i+1:])
+	}
+	if keyword == "" || arg == "" {
+		return "", "", false
+	}
+	return keyword, arg, true
+}
+
+// dircolorsKeywords are the dircolors keywords of the indicators returned
+// by LSColors.indicators, in the same order.
+var dircolorsKeywords = [...]string{
+	"DIR", "FILE", "LINK", "FIFO", "SOCK",
+	"BLK", "CHR", "ORPHAN", "MISSING", "EXEC",
+	"STICKY_OTHER_WRITABLE", "NORMAL", "STICKY", "OTHER_WRITABLE", "RESET",
+}
+
+// Dircolors returns c in the dircolors database format parsed by
+// ParseDircolors, with one "KEYWORD sequence" entry per line. Extensions
+// that start with a '.' are written as such (".tar 01;31") and all other
+// extensions and globs as patterns ("*README 01"). Globs that do not start
+// with a '*' (such as ".git*") cannot be represented since dircolors treats
+// a leading '.' as an extension and are omitted.
+func (c *LSColors) Dircolors() string {
+	var w strings.Builder
+	entry := func(keyword, seq string) {
+		w.WriteString(keyword)
+		w.WriteByte(' ')
+		w.WriteString(seq)
+		w.WriteByte('\n')
+	}
+	for i, e := range c.indicators() {
+		if e.Seq != "" {
+			entry(dircolorsKeywords[i], e.Seq)
+		}
+	}
+	for _, e := range c.Exts {
+		if strings.HasPrefix(e.Ext, ".") {
+			entry(e.Ext, e.Seq)
+		} else {
+			entry("*"+e.Ext, e.Seq)
+		}
+	}
+	for _, e := range c.Globs {
+		if strings.HasPrefix(e.Ext, "*") {
+			entry(e.Ext, e.Seq)
+		}
+	}
+	return w.String()
+}
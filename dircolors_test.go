@@ -0,0 +1,151 @@
+package lscolors
+
+import (
+	"strings"
+	"testing"
+)
+
+const testDircolors = `# Configuration file for dircolors
+COLOR tty
+TERM linux
+TERM xterm*
+
+RESET 0 # reset to "normal" color
+DIR 01;34
+LINK 01;36
+FIFO 40;33
+EXEC 01;32
+LEFTCODE \e[
+
+TERM dumb
+DIR 00
+*.gz 01;31
+
+COLORTERM ?*
+TERM vt100
+.tar	01;31
+*README 1
+.git* 2
+`
+
+func TestParseDircolors(t *testing.T) {
+	tests := []struct {
+		term, colorterm string
+		want            string
+	}{
+		{"xterm-256color", "", "di=01;34:ln=01;36:pi=40;33:ex=01;32:rs=0"},
+		{"xterm-256color", "truecolor", "di=01;34:ln=01;36:pi=40;33:ex=01;32:rs=0:*.tar=01;31:*README=1:*.git*=2"},
+		{"dumb", "", "di=00:*.gz=01;31"},
+		{"vt100", "", "*.tar=01;31:*README=1:*.git*=2"},
+		{"screen", "", ""},
+	}
+	for _, x := range tests {
+		ls, err := ParseDircolorsTerm(strings.NewReader(testDircolors), x.term, x.colorterm)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s := ls.String(); s != x.want {
+			t.Errorf("TERM=%q COLORTERM=%q:\ngot:  %q\nwant: %q", x.term, x.colorterm, s, x.want)
+		}
+	}
+}
+
+func TestParseDircolorsGlobal(t *testing.T) {
+	// Entries before the first TERM line apply to all terminals.
+	const db = "DIR 01;34\nTERM nope\nLINK 01;36\n"
+	ls, err := ParseDircolorsTerm(strings.NewReader(db), "xterm", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := ls.String(); s != "di=01;34" {
+		t.Errorf("String() = %q; want: %q", s, "di=01;34")
+	}
+}
+
+func TestParseDircolorsInvalid(t *testing.T) {
+	const db = "DIR 01;34\nLINK red\n.go 32\n"
+	ls, err := ParseDircolorsTerm(strings.NewReader(db), "xterm", "")
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if !strings.Contains(err.Error(), "LINK red") {
+		t.Errorf("error %q does not mention the invalid entry", err)
+	}
+	if s := ls.String(); s != "di=01;34:*.go=32" {
+		t.Errorf("String() = %q; want: %q", s, "di=01;34:*.go=32")
+	}
+}
+
+func TestDircolors(t *testing.T) {
+	const colors = "di=01;34:ln=01;36:or=31:ex=01;32:tw=30;42:rs=0:" +
+		"*.tar=01;31:*README=1:*.min.*=2:.git*=3"
+	ls, err := ParseLSColors(colors)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db := ls.Dircolors()
+	for _, line := range []string{"DIR 01;34\n", "STICKY_OTHER_WRITABLE 30;42\n", ".tar 01;31\n", "*README 1\n"} {
+		if !strings.Contains(db, line) {
+			t.Errorf("Dircolors() = %q; missing: %q", db, line)
+		}
+	}
+	if strings.Contains(db, ".git*") {
+		t.Errorf("Dircolors() = %q; should omit glob %q", db, ".git*")
+	}
+
+	got, err := ParseDircolorsTerm(strings.NewReader(db), "xterm", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ls.Disable(".git*")
+	if got.String() != ls.String() {
+		t.Errorf("round trip:\ngot:  %q\nwant: %q", got.String(), ls.String())
+	}
+}
+
+func TestDecodeEscapes(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{`\e[`, "\x1b["},
+		{`\033[`, "\x1b["},
+		{`\33[`, "\x1b["},
+		{`\x1b[`, "\x1b["},
+		{`\X1B[`, "\x1b["},
+		{`^[[`, "\x1b["},
+		{`^?`, "\x7f"},
+		{`m`, "m"},
+		{`\e[0m`, "\x1b[0m"},
+		{`a\_b\tc\\`, "a b\tc\\"},
+		{`\1011`, "A1"}, // at most 3 octal digits
+	}
+	for _, x := range tests {
+		got, ok := decodeEscapes(x.in)
+		if !ok || got != x.want {
+			t.Errorf("decodeEscapes(%q) = %q, %t; want: %q, true", x.in, got, ok, x.want)
+		}
+	}
+	for _, s := range []string{`\`, `^`, `^ `, `\xg`} {
+		if got, ok := decodeEscapes(s); ok {
+			t.Errorf("decodeEscapes(%q) = %q, %t; want an error", s, got, ok)
+		}
+	}
+}
+
+func TestParseDircolorsCodes(t *testing.T) {
+	const db = "LEFTCODE \\033[\nRIGHTCODE m\nENDCODE ^[[0m\nDIR 01;34\n"
+	ls, err := ParseDircolorsTerm(strings.NewReader(db), "xterm", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ls.LeftCode != "\x1b[" || ls.RightCode != "m" || ls.EndCode != "\x1b[0m" {
+		t.Errorf("codes = %q, %q, %q; want: %q, %q, %q",
+			ls.LeftCode, ls.RightCode, ls.EndCode, "\x1b[", "m", "\x1b[0m")
+	}
+	if s := ls.String(); s != "di=01;34" {
+		t.Errorf("String() = %q; want: %q", s, "di=01;34")
+	}
+	if _, err := ParseDircolorsTerm(strings.NewReader("LEFTCODE \\x\n"), "xterm", ""); err == nil {
+		t.Error("expected an error for an invalid escape")
+	}
+}
@@ -0,0 +1,82 @@
+package lscolors
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+)
+
+// matchExtFold is like MatchExt but ignores case.
+func (c *ColorExtension) matchExtFold(name string) bool {
+	i := len(name)
+	j := len(c.Ext)
+	return j > 0 && j <= i && strings.EqualFold(name[i-j:], c.Ext) &&
+		(c.Ext[0] < utf8.RuneSelf || runeBoundary(name, i-j))
+}
+
+// matchExtFold is like matchExt but ignores case (see FoldCase).
+func (c *LSColors) matchExtFold(name string) *ColorExtension {
+	var sfx *ColorExtension
+	for i := range c.Exts {
+		e := &c.Exts[i]
+		if len(e.Ext) > len(name) {
+			break
+		}
+		if e.matchExtFold(name) {
+			sfx = e
+		}
+	}
+	return sfx
+}
+
+// matchNameFold returns the Names rule that matches name ignoring case. If
+// several names match, the one that sorts last wins, like in matchExtFold,
+// so that the result does not depend on the map's iteration order.
+func (c *LSColors) matchNameFold(name string) *ColorExtension {
+	var match *ColorExtension
+	var matchKey string
+	for key, e := range c.Names {
+		if e != nil && strings.EqualFold(key, name) && (match == nil || key > matchKey) {
+			match, matchKey = e, key
+		}
+	}
+	return match
+}
+
+// matchGlobFold is like matchGlob but ignores case.
+func (c *LSColors) matchGlobFold(name string) *ColorExtension {
+	name = strings.ToLower(name)
+	for i := range c.Globs {
+		if ok, _ := path.Match(strings.ToLower(c.Globs[i].Ext), name); ok {
+			return &c.Globs[i]
+		}
+	}
+	return nil
+}
+
+// DetectCaseInsensitive reports if the filesystem containing the directory
+// dir compares names without regard to case, by creating a temporary file
+// in dir and looking it up with a different case. It returns false if the
+// file cannot be created, for example because dir is read-only.
+func DetectCaseInsensitive(dir string) bool {
+	f, err := os.CreateTemp(dir, ".lscolors-case-*")
+	if err != nil {
+		return false
+	}
+	name := f.Name()
+	defer os.Remove(name)
+	fi, err := f.Stat()
+	f.Close()
+	if err != nil {
+		return false
+	}
+	base := filepath.Base(name)
+	upper := strings.ToUpper(base)
+	if upper == base {
+		return false
+	}
+	ufi, err := os.Stat(filepath.Join(filepath.Dir(name), upper))
+	return err == nil && os.SameFile(fi, ufi)
+}
@@ -0,0 +1,90 @@
+package lscolors
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFoldCase(t *testing.T) {
+	ls, err := ParseLSColors("*.jpg=35:*.tar.gz=31:*Makefile=33:*.min.*=2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name       string
+		want, fold string
+	}{
+		{"a.jpg", "35", "35"},
+		{"IMG.JPG", "", "35"},
+		{"a.TAR.GZ", "", "31"},
+		{"MAKEFILE", "", "33"},
+		{"app.MIN.js", "", "2"},
+		{"a.png", "", ""},
+	}
+	for _, x := range tests {
+		d := testDirEntry{name: x.name}
+		ls.FoldCase = false
+		if e := ls.MatchEntry(x.name, d); e.Seq != x.want {
+			t.Errorf("MatchEntry(%q) = %q; want: %q", x.name, e.Seq, x.want)
+		}
+		ls.FoldCase = true
+		if e := ls.MatchEntry(x.name, d); e.Seq != x.fold {
+			t.Errorf("FoldCase: MatchEntry(%q) = %q; want: %q", x.name, e.Seq, x.fold)
+		}
+	}
+
+	// The name is displayed unmodified
+	ls.FoldCase = true
+	if got, want := ls.MatchEntry("IMG.JPG", testDirEntry{name: "IMG.JPG"}).Format("IMG.JPG"),
+		"\x1b[35mIMG.JPG\x1b[0m"; got != want {
+		t.Errorf("Format = %q; want: %q", got, want)
+	}
+}
+
+func TestFoldCaseNames(t *testing.T) {
+	ls, err := ParseLSColors("*Makefile=31:*MAKEFILE=32:*makefile=33")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ls.FoldCase = true
+	d := testDirEntry{name: "MakeFile"}
+	// Names that differ only by case: the one that sorts last wins
+	for i := 0; i < 20; i++ {
+		if e := ls.MatchEntry("MakeFile", d); e.Seq != "33" {
+			t.Fatalf("MatchEntry(%q) = %q; want: %q", d.name, e.Seq, "33")
+		}
+	}
+	if e := ls.MatchEntry("Makefile", testDirEntry{name: "Makefile"}); e.Seq != "31" {
+		t.Errorf("exact match: MatchEntry = %q; want: %q", e.Seq, "31")
+	}
+}
+
+func TestDetectCaseInsensitive(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "file"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	_, err := os.Stat(filepath.Join(dir, "FILE"))
+	want := err == nil
+	if got := DetectCaseInsensitive(dir); got != want {
+		t.Errorf("DetectCaseInsensitive(%q) = %t; want: %t", dir, got, want)
+	}
+	if !want {
+		t.Skip("the temporary directory is on a case-sensitive filesystem")
+	}
+	ls, err := ParseLSColors("*.jpg=35")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ls.FoldCase = DetectCaseInsensitive(dir)
+	if e := ls.MatchEntry("IMG.JPG", testDirEntry{name: "IMG.JPG"}); e.Seq != "35" {
+		t.Errorf("MatchEntry(IMG.JPG) = %q; want: %q", e.Seq, "35")
+	}
+}
+
+func TestDetectCaseInsensitiveError(t *testing.T) {
+	if DetectCaseInsensitive(filepath.Join(t.TempDir(), "missing")) {
+		t.Error("DetectCaseInsensitive(missing) = true; want: false")
+	}
+}
@@ -0,0 +1,143 @@
+package lscolors
+
+import (
+	"io/fs"
+	"os"
+)
+
+// A ColorMode is the caller's choice of when a Formatter emits colors, like
+// the --color option of GNU ls.
+type ColorMode int
+
+const (
+	ColorAuto   ColorMode = iota // use DetectColorEnabled
+	ColorAlways                  // always emit colors
+	ColorNever                   // never emit colors
+)
+
+// DetectColorEnabled reports if colors should be written to f. Colors are
+// disabled if the NO_COLOR environment variable is set to a non-empty value
+// (https://no-color.org), otherwise they are enabled if FORCE_COLOR is set
+// to a non-empty value (https://force-color.org) or if f is a terminal.
+func DetectColorEnabled(f *os.File) bool {
+	return detectColorEnabled(os.Getenv, isTerminal(f))
+}
+
+func detectColorEnabled(getenv func(string) string, tty bool) bool {
+	if getenv("NO_COLOR") != "" {
+		return false
+	}
+	if getenv("FORCE_COLOR") != "" {
+		return true
+	}
+	return tty
+}
+
+// isTerminal reports if f is a character device, which is the case for
+// terminals. It is a cheap approximation of isatty(3).
+func isTerminal(f *os.File) bool {
+	if f == nil {
+		return false
+	}
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&fs.ModeCharDevice != 0
+}
+
+// A Formatter formats file names using the colors of an LSColors if colors
+// are enabled.
+type Formatter struct {
+	Colors *LSColors
+
+	// Quote is how names containing control characters are quoted. It
+	// should be set when names may come from untrusted sources since they
+	// may contain escape sequences.
+	Quote QuotingStyle
+
+	// Pager restricts the output to SGR color sequences so that it is safe
+	// to pipe to a pager such as "less -R", which only interprets those.
+	// Sequences that are not valid SGR parameters are not emitted, and
+	// names are quoted with QuoteEscape if Quote is QuoteNone so that
+	// escape sequences embedded in names cannot reach the pager.
+	Pager bool
+
+	// Terminfo, if set, is used to write colors with the sequences of the
+	// terminal instead of ANSI sequences (see LoadTerminfo). Colors whose
+	// attributes the terminal does not support are written using ANSI
+	// sequences, as are all colors if Terminfo is nil or Pager is set.
+	Terminfo *Terminfo
+
+	enabled bool
+}
+
+// NewFormatter returns a Formatter that writes names colored by c to out.
+// Whether colors are used is decided once by mode, which takes precedence
+// over the environment, and for ColorAuto by DetectColorEnabled(out).
+func NewFormatter(c *LSColors, out *os.File, mode ColorMode) *Formatter {
+	enabled := mode == ColorAlways
+	if mode == ColorAuto {
+		enabled = DetectColorEnabled(out)
+	}
+	return &Formatter{Colors: c, enabled: enabled}
+}
+
+// Enabled reports if f emits colors.
+func (f *Formatter) Enabled() bool { return f.enabled }
+
+// AppendEntry appends the name of d, quoted by f.Quote and colored by its
+// type if colors are enabled, to b and returns the extended buffer.
+func (f *Formatter) AppendEntry(b []byte, path string, d fs.DirEntry) []byte {
+	name := f.quote(d.Name())
+	e := f.match(path, d)
+	if e == nil {
+		return append(b, name...)
+	}
+	if set, reset, ok := f.terminfo(e); ok {
+		b = append(b, set...)
+		b = append(b, name...)
+		return append(b, reset...)
+	}
+	return e.AppendFormat(b, name)
+}
+
+// FormatEntry returns the name of d quoted by f.Quote and colored by its
+// type if colors are enabled.
+func (f *Formatter) FormatEntry(path string, d fs.DirEntry) string {
+	name := f.quote(d.Name())
+	e := f.match(path, d)
+	if e == nil {
+		return name
+	}
+	if set, reset, ok := f.terminfo(e); ok {
+		return set + name + reset
+	}
+	return e.Format(name)
+}
+
+// quote quotes name using f.Quote.
+func (f *Formatter) quote(name string) string {
+	if f.Pager && f.Quote == QuoteNone {
+		return QuoteName(name, QuoteEscape)
+	}
+	return QuoteName(name, f.Quote)
+}
+
+// terminfo returns the terminal specific sequences for e, if f.Terminfo is
+// set and supports them. Pager output always uses ANSI sequences.
+func (f *Formatter) terminfo(e *ColorExtension) (set, reset string, ok bool) {
+	if f.Terminfo == nil || f.Pager || e.Seq == "" {
+		return "", "", false
+	}
+	return f.Terminfo.Sequence(e.Seq)
+}
+
+// match returns the color of d or nil if it should not be colored.
+func (f *Formatter) match(path string, d fs.DirEntry) *ColorExtension {
+	if !f.enabled {
+		return nil
+	}
+	e := f.Colors.MatchEntry(path, d)
+	if f.Pager && e.Seq != "" && !validSequence(e.Seq) {
+		return nil
+	}
+	return e
+}
@@ -0,0 +1,121 @@
+package lscolors
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDetectColorEnabled(t *testing.T) {
+	tests := []struct {
+		noColor, forceColor string
+		tty, want           bool
+	}{
+		{"", "", false, false},
+		{"", "", true, true},
+		{"", "1", false, true},
+		{"", "0", false, true}, // any non-empty value forces color
+		{"", "1", true, true},
+		{"1", "", true, false},
+		{"1", "1", true, false},
+		{"1", "1", false, false},
+	}
+	for _, x := range tests {
+		env := map[string]string{"NO_COLOR": x.noColor, "FORCE_COLOR": x.forceColor}
+		getenv := func(key string) string { return env[key] }
+		if got := detectColorEnabled(getenv, x.tty); got != x.want {
+			t.Errorf("NO_COLOR=%q FORCE_COLOR=%q tty=%t: got: %t; want: %t",
+				x.noColor, x.forceColor, x.tty, got, x.want)
+		}
+	}
+}
+
+func TestNewFormatter(t *testing.T) {
+	f, err := os.Create(filepath.Join(t.TempDir(), "out"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	ls, err := ParseLSColors("di=01;34")
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := testDirEntry{name: "dir", mode: os.ModeDir}
+	tests := []struct {
+		mode                ColorMode
+		noColor, forceColor string
+		want                bool
+	}{
+		{ColorAuto, "", "", false}, // not a terminal
+		{ColorAuto, "", "1", true},
+		{ColorAuto, "1", "1", false},
+		{ColorAlways, "1", "", true},
+		{ColorNever, "", "1", false},
+	}
+	for _, x := range tests {
+		t.Setenv("NO_COLOR", x.noColor)
+		t.Setenv("FORCE_COLOR", x.forceColor)
+		fm := NewFormatter(ls, f, x.mode)
+		if fm.Enabled() != x.want {
+			t.Errorf("%+v: Enabled() = %t; want: %t", x, fm.Enabled(), x.want)
+		}
+		want := "dir"
+		if x.want {
+			want = ls.DI.Format("dir")
+		}
+		if s := fm.FormatEntry("dir", d); s != want {
+			t.Errorf("%+v: FormatEntry = %q; want: %q", x, s, want)
+		}
+		if s := string(fm.AppendEntry(nil, "dir", d)); s != want {
+			t.Errorf("%+v: AppendEntry = %q; want: %q", x, s, want)
+		}
+	}
+}
+
+func TestFormatterPager(t *testing.T) {
+	ls, err := ParseLSColors("di=01;34:*.go=32")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Sequences set directly are not validated
+	ls.FI = ColorExtension{Ext: "fi", Seq: "0\x1b[2J"}
+	entries := []testDirEntry{
+		{name: "dir", mode: os.ModeDir},
+		{name: "main.go"},
+		{name: "file"},
+		{name: "evil\x1b]8;;http://x\a.go"},
+		{name: "clear\x1b[2J.go"},
+	}
+	f := &Formatter{Colors: ls, Pager: true, enabled: true}
+	var b []byte
+	for _, d := range entries {
+		b = f.AppendEntry(b, d.name, d)
+		b = append(b, '\n')
+		if s, want := f.FormatEntry(d.name, d), string(f.AppendEntry(nil, d.name, d)); s != want {
+			t.Errorf("FormatEntry(%q) = %q; want: %q", d.name, s, want)
+		}
+	}
+	out := string(b)
+	for s := out; ; {
+		i := strings.IndexByte(s, '\x1b')
+		if i < 0 {
+			break
+		}
+		n := escapeLen(s[i:])
+		if esc := s[i : i+n]; len(esc) < 3 || esc[1] != '[' || esc[n-1] != 'm' || !validSequence(esc[2:n-1]) {
+			t.Errorf("non-SGR escape %q in output:\n%q", esc, out)
+		}
+		s = s[i+n:]
+	}
+	if !strings.Contains(out, ls.DI.Format("dir")) || !strings.Contains(out, "\nfile\n") {
+		t.Errorf("unexpected output: %q", out)
+	}
+
+	// An explicit quoting style is respected
+	f.Quote = QuoteShell
+	if s, want := f.FormatEntry("file", entries[4]), QuoteName(entries[4].name, QuoteShell); !strings.Contains(s, want) {
+		t.Errorf("FormatEntry = %q; want it to contain: %q", s, want)
+	}
+}
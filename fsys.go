@@ -0,0 +1,26 @@
+package lscolors
+
+import "io/fs"
+
+// MatchFS is like MatchInfo but matches the file at path in fsys, such as
+// an fstest.MapFS or an embed.FS, instead of the operating system's
+// filesystem. Symbolic links are only detected if fsys implements
+// fs.ReadLinkFS and the package is built with Go 1.25 or later, and broken
+// links are detected by calling fs.Stat on fsys.
+func (c *LSColors) MatchFS(fsys fs.FS, path string) (*ColorExtension, error) {
+	fi, err := lstatFS(fsys, path)
+	if err != nil {
+		return nil, err
+	}
+	return c.matchInfo(path, fi, fsEntry{fs.FileInfoToDirEntry(fi), fsys, path}), nil
+}
+
+// An fsEntry is a DirEntry with a Stat method that follows symbolic links
+// in fsys (see statLink).
+type fsEntry struct {
+	fs.DirEntry
+	fsys fs.FS
+	path string
+}
+
+func (e fsEntry) Stat() (fs.FileInfo, error) { return fs.Stat(e.fsys, e.path) }
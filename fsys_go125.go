@@ -0,0 +1,11 @@
+//go:build go1.25
+
+package lscolors
+
+import "io/fs"
+
+// lstatFS returns the FileInfo of name in fsys without following symbolic
+// links.
+func lstatFS(fsys fs.FS, name string) (fs.FileInfo, error) {
+	return fs.Lstat(fsys, name)
+}
@@ -0,0 +1,11 @@
+//go:build !go1.25
+
+package lscolors
+
+import "io/fs"
+
+// lstatFS returns the FileInfo of name in fsys. The io/fs package does not
+// support symbolic links before Go 1.25 so they are followed.
+func lstatFS(fsys fs.FS, name string) (fs.FileInfo, error) {
+	return fs.Stat(fsys, name)
+}
@@ -0,0 +1,55 @@
+//go:build go1.25
+
+package lscolors
+
+import (
+	"errors"
+	"io/fs"
+	"testing"
+	"testing/fstest"
+)
+
+func TestMatchFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"dir/main.go":  {},
+		"dir/run":      {Mode: 0755},
+		"dir/link":     {Mode: fs.ModeSymlink, Data: []byte("main.go")},
+		"dir/dirlink":  {Mode: fs.ModeSymlink, Data: []byte("../sub")},
+		"dir/broken":   {Mode: fs.ModeSymlink, Data: []byte("missing")},
+		"sub/file.tar": {},
+	}
+	ls, err := ParseLSColors("di=01;34:ln=01;36:or=31:ex=01;32:*.go=32:*.tar=01;31")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"dir", "01;34"},
+		{"dir/main.go", "32"},
+		{"dir/run", "01;32"},
+		{"dir/link", "01;36"},
+		{"dir/dirlink", "01;36"},
+		{"dir/broken", "31"},
+		{"sub/file.tar", "01;31"},
+	}
+	for _, x := range tests {
+		e, err := ls.MatchFS(fsys, x.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if e.Seq != x.want {
+			t.Errorf("MatchFS(%q) = %q; want: %q", x.path, e.Seq, x.want)
+		}
+	}
+
+	ls.FollowLinks = true
+	if e, err := ls.MatchFS(fsys, "dir/dirlink"); err != nil || e != &ls.DI {
+		t.Errorf("FollowLinks: MatchFS(dir/dirlink) = %+v, %v; want: %+v", e, err, ls.DI)
+	}
+
+	if _, err := ls.MatchFS(fsys, "missing"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("MatchFS(missing) = %v; want: %v", err, fs.ErrNotExist)
+	}
+}
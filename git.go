@@ -0,0 +1,47 @@
+package lscolors
+
+import "io/fs"
+
+// GitStatus is the status of a file in a Git working tree. Computing it is
+// left to the caller.
+type GitStatus int
+
+const (
+	GitUnmodified GitStatus = iota
+	GitModified
+	GitUntracked
+	GitIgnored
+	GitStaged
+)
+
+// GitColors are the colors used by MatchWithGitStatus. Unset colors fall
+// back to the normal palette.
+type GitColors struct {
+	Modified  ColorExtension
+	Untracked ColorExtension
+	Ignored   ColorExtension
+	Staged    ColorExtension
+}
+
+// MatchWithGitStatus is like MatchEntry but colors the file according to
+// its Git status if c.Git has a color for it. Unmodified files, and files
+// whose status has no color, are colored normally.
+func (c *LSColors) MatchWithGitStatus(path string, d fs.DirEntry, status GitStatus) *ColorExtension {
+	if g := c.Git; g != nil {
+		var e *ColorExtension
+		switch status {
+		case GitModified:
+			e = &g.Modified
+		case GitUntracked:
+			e = &g.Untracked
+		case GitIgnored:
+			e = &g.Ignored
+		case GitStaged:
+			e = &g.Staged
+		}
+		if e != nil && !e.Empty() {
+			return e
+		}
+	}
+	return c.MatchEntry(path, d)
+}
@@ -0,0 +1,38 @@
+package lscolors
+
+import "testing"
+
+func TestMatchWithGitStatus(t *testing.T) {
+	ls, err := ParseLSColors("fi=0:*.go=32")
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := testDirEntry{name: "main.go"}
+	statuses := []GitStatus{GitUnmodified, GitModified, GitUntracked, GitIgnored, GitStaged}
+
+	// Without GitColors the palette is used for every status
+	for _, status := range statuses {
+		if e := ls.MatchWithGitStatus("main.go", d, status); e.Seq != "32" {
+			t.Errorf("MatchWithGitStatus(%d) = %q; want: %q", status, e.Seq, "32")
+		}
+	}
+
+	ls.Git = &GitColors{
+		Modified:  ColorExtension{Seq: "33"},
+		Untracked: ColorExtension{Seq: "31"},
+		Ignored:   ColorExtension{Seq: "02"},
+		// Staged is not set
+	}
+	want := map[GitStatus]string{
+		GitUnmodified: "32",
+		GitModified:   "33",
+		GitUntracked:  "31",
+		GitIgnored:    "02",
+		GitStaged:     "32",
+	}
+	for _, status := range statuses {
+		if e := ls.MatchWithGitStatus("main.go", d, status); e.Seq != want[status] {
+			t.Errorf("MatchWithGitStatus(%d) = %q; want: %q", status, e.Seq, want[status])
+		}
+	}
+}
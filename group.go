@@ -0,0 +1,88 @@
+package lscolors
+
+import (
+	"io/fs"
+	"strings"
+)
+
+// Coarse file groups returned by Group.
+const (
+	GroupArchive  = "archive"
+	GroupImage    = "image"
+	GroupAudio    = "audio"
+	GroupVideo    = "video"
+	GroupCode     = "code"
+	GroupDocument = "document"
+)
+
+// DefaultGroups maps lower case file extensions to coarse groups. It is
+// used by Group when LSColors.Groups is nil.
+var DefaultGroups = map[string]string{
+	// Archives
+	".7z": GroupArchive, ".bz2": GroupArchive, ".deb": GroupArchive,
+	".gz": GroupArchive, ".jar": GroupArchive, ".rar": GroupArchive,
+	".rpm": GroupArchive, ".tar": GroupArchive, ".tar.gz": GroupArchive,
+	".tbz2": GroupArchive, ".tgz": GroupArchive, ".xz": GroupArchive,
+	".zip": GroupArchive, ".zst": GroupArchive,
+
+	// Images
+	".bmp": GroupImage, ".gif": GroupImage, ".ico": GroupImage,
+	".jpeg": GroupImage, ".jpg": GroupImage, ".png": GroupImage,
+	".svg": GroupImage, ".tif": GroupImage, ".tiff": GroupImage,
+	".webp": GroupImage,
+
+	// Audio
+	".aac": GroupAudio, ".flac": GroupAudio, ".m4a": GroupAudio,
+	".mid": GroupAudio, ".mp3": GroupAudio, ".ogg": GroupAudio,
+	".opus": GroupAudio, ".wav": GroupAudio,
+
+	// Video
+	".avi": GroupVideo, ".flv": GroupVideo, ".m4v": GroupVideo,
+	".mkv": GroupVideo, ".mov": GroupVideo, ".mp4": GroupVideo,
+	".mpeg": GroupVideo, ".mpg": GroupVideo, ".webm": GroupVideo,
+	".wmv": GroupVideo,
+
+	// Code
+	".c": GroupCode, ".cc": GroupCode, ".cpp": GroupCode, ".cs": GroupCode,
+	".go": GroupCode, ".h": GroupCode, ".hpp": GroupCode, ".java": GroupCode,
+	".js": GroupCode, ".kt": GroupCode, ".lua": GroupCode, ".php": GroupCode,
+	".pl": GroupCode, ".py": GroupCode, ".rb": GroupCode, ".rs": GroupCode,
+	".sh": GroupCode, ".swift": GroupCode, ".ts": GroupCode, ".zsh": GroupCode,
+
+	// Documents
+	".doc": GroupDocument, ".docx": GroupDocument, ".epub": GroupDocument,
+	".md": GroupDocument, ".odt": GroupDocument, ".pdf": GroupDocument,
+	".ppt": GroupDocument, ".pptx": GroupDocument, ".rtf": GroupDocument,
+	".txt": GroupDocument, ".xls": GroupDocument, ".xlsx": GroupDocument,
+}
+
+// Group returns the coarse group, such as "archive" or "image", of the file
+// name based on its extension, or "" if it is not in a group. The longest
+// matching extension is used (".tar.gz" before ".gz") and matching is case
+// insensitive. The extensions are looked up in c.Groups or, if that is nil,
+// DefaultGroups.
+func (c *LSColors) Group(name string) string {
+	groups := c.Groups
+	if groups == nil {
+		groups = DefaultGroups
+	}
+	// Skip the first byte so that dotfiles like ".zip" have no extension.
+	for i := 1; i < len(name); i++ {
+		if name[i] == '.' {
+			if g, ok := groups[strings.ToLower(name[i:])]; ok {
+				return g
+			}
+		}
+	}
+	return ""
+}
+
+// MatchGroup is like MatchEntry but also returns the group of the file (see
+// Group). Only regular files have a group.
+func (c *LSColors) MatchGroup(path string, d fs.DirEntry) (*ColorExtension, string) {
+	ext := c.MatchEntry(path, d)
+	if !d.Type().IsRegular() {
+		return ext, ""
+	}
+	return ext, c.Group(d.Name())
+}
@@ -0,0 +1,50 @@
+package lscolors
+
+import (
+	"io/fs"
+	"testing"
+)
+
+func TestGroup(t *testing.T) {
+	ls, err := ParseLSColors("di=01;34:*.tar.gz=01;31:*.go=32")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name, group string
+	}{
+		{"a.tar.gz", GroupArchive},
+		{"a.ZIP", GroupArchive},
+		{"photo.jpeg", GroupImage},
+		{"song.flac", GroupAudio},
+		{"movie.mkv", GroupVideo},
+		{"main.go", GroupCode},
+		{"paper.pdf", GroupDocument},
+		{"v1.2.tar.gz", GroupArchive},
+		{"Makefile", ""},
+		{".zip", ""},
+		{"a.unknown", ""},
+	}
+	for _, x := range tests {
+		if g := ls.Group(x.name); g != x.group {
+			t.Errorf("Group(%q) = %q; want: %q", x.name, g, x.group)
+		}
+	}
+
+	ext, g := ls.MatchGroup("a.tar.gz", testDirEntry{name: "a.tar.gz"})
+	if ext.Seq != "01;31" || g != GroupArchive {
+		t.Errorf("MatchGroup(a.tar.gz) = %q, %q; want: %q, %q", ext.Seq, g, "01;31", GroupArchive)
+	}
+	ext, g = ls.MatchGroup("d.zip", testDirEntry{name: "d.zip", mode: fs.ModeDir})
+	if ext != &ls.DI || g != "" {
+		t.Errorf("MatchGroup(dir) = %+v, %q; want: %+v, %q", ext, g, ls.DI, "")
+	}
+
+	ls.Groups = map[string]string{".go": "golang"}
+	if g := ls.Group("main.go"); g != "golang" {
+		t.Errorf("Group(main.go) = %q; want: %q", g, "golang")
+	}
+	if g := ls.Group("a.zip"); g != "" {
+		t.Errorf("Group(a.zip) = %q; want: %q", g, "")
+	}
+}
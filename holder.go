@@ -0,0 +1,36 @@
+package lscolors
+
+import "sync/atomic"
+
+// A PaletteHolder holds an *LSColors that may be replaced while it is being
+// read by other goroutines, such as when a long running program re-reads
+// LS_COLORS on a signal. Readers always see a complete palette.
+//
+// A palette must not be modified after it is passed to Store since readers
+// access it without locking. To change a palette, parse or copy a new one
+// and Store it instead. Store and NewPaletteHolder finalize the palette
+// (see LSColors.Finalize) so that palettes built by hand are not modified
+// by the first reader that matches against them.
+//
+// The zero value is ready to use and holds a nil palette.
+type PaletteHolder struct {
+	p atomic.Pointer[LSColors]
+}
+
+// NewPaletteHolder returns a PaletteHolder holding ls.
+func NewPaletteHolder(ls *LSColors) *PaletteHolder {
+	h := new(PaletteHolder)
+	h.Store(ls)
+	return h
+}
+
+// Load returns the current palette.
+func (h *PaletteHolder) Load() *LSColors { return h.p.Load() }
+
+// Store replaces the current palette with ls, finalizing it first.
+func (h *PaletteHolder) Store(ls *LSColors) {
+	if ls != nil && !ls.finalized {
+		ls.Finalize()
+	}
+	h.p.Store(ls)
+}
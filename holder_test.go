@@ -0,0 +1,97 @@
+package lscolors
+
+import (
+	"io/fs"
+	"sync"
+	"testing"
+)
+
+func TestPaletteHolder(t *testing.T) {
+	var h PaletteHolder
+	if ls := h.Load(); ls != nil {
+		t.Fatalf("Load() = %v; want: nil", ls)
+	}
+
+	palettes := make([]*LSColors, 2)
+	for i, s := range []string{"di=01;34:*.go=32", "di=01;35:*.go=33"} {
+		ls, err := ParseLSColors(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		palettes[i] = ls
+	}
+	h.Store(palettes[0])
+
+	dir := testDirEntry{name: "dir", mode: fs.ModeDir}
+	file := testDirEntry{name: "main.go"}
+
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				ls := h.Load()
+				d := ls.MatchEntry("dir", dir).Seq
+				f := ls.MatchEntry("main.go", file).Seq
+				// Both colors must come from the same palette.
+				if !(d == "01;34" && f == "32") && !(d == "01;35" && f == "33") {
+					t.Errorf("inconsistent palette: di=%q *.go=%q", d, f)
+					return
+				}
+			}
+		}()
+	}
+	for i := 0; i < 1000; i++ {
+		h.Store(palettes[i%2])
+	}
+	close(done)
+	wg.Wait()
+
+	if ls := NewPaletteHolder(palettes[1]).Load(); ls != palettes[1] {
+		t.Errorf("NewPaletteHolder().Load() = %p; want: %p", ls, palettes[1])
+	}
+}
+
+func TestPaletteHolderHandBuilt(t *testing.T) {
+	// Exts is deliberately unsorted and contains a duplicate so that
+	// matching an unfinalized palette would modify it.
+	ls := &LSColors{
+		DI: ColorExtension{Ext: "di", Seq: "01;34"},
+		Exts: []ColorExtension{
+			{Ext: ".gz", Seq: "31"},
+			{Ext: ".tar.gz", Seq: "01;31"},
+			{Ext: ".go", Seq: "33"},
+			{Ext: ".go", Seq: "32"},
+		},
+	}
+	h := NewPaletteHolder(ls)
+	file := testDirEntry{name: "a.tar.gz"}
+	src := testDirEntry{name: "main.go"}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				ls := h.Load()
+				if e := ls.MatchEntry("a.tar.gz", file); e.Seq != "01;31" {
+					t.Errorf("MatchEntry(a.tar.gz) = %q; want: %q", e.Seq, "01;31")
+					return
+				}
+				if e := ls.MatchEntry("main.go", src); e.Seq != "32" {
+					t.Errorf("MatchEntry(main.go) = %q; want: %q", e.Seq, "32")
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,59 @@
+package lscolors
+
+import (
+	"io/fs"
+	"path/filepath"
+)
+
+// An IconSet maps files to icons, such as Nerd Font glyphs, for
+// FormatWithIcon. The package only chooses the icon: the glyphs are
+// provided by the caller.
+type IconSet struct {
+	// Icons maps keys to icons. A file's icon is found by looking up, in
+	// order, the key of the rule that colors it as returned by
+	// MatchEntryKey ("di", "ln", "*.go") and, for files that are not
+	// directories, the key of its extension ("*.go").
+	Icons map[string]string
+
+	// Default is the icon of files that have no icon in Icons. If empty,
+	// such files have no icon.
+	Default string
+
+	// Color is the color of the icons. If it has no sequence the icon has
+	// the same color as the file's name.
+	Color ColorExtension
+}
+
+// icon returns the icon for the file name, which was matched by key.
+func (s *IconSet) icon(name, key string, typ fs.FileMode) string {
+	if icon, ok := s.Icons[key]; ok {
+		return icon
+	}
+	if !typ.IsDir() {
+		if ext := filepath.Ext(name); ext != "" {
+			if icon, ok := s.Icons["*"+ext]; ok {
+				return icon
+			}
+		}
+	}
+	return s.Default
+}
+
+// FormatWithIcon is like MatchEntry(path, d).Format(name) but prepends the
+// icon chosen by icons, followed by a space, to the colored name. The icon
+// is colored with icons.Color or, if it is not set, the color of the name.
+func (c *LSColors) FormatWithIcon(path, name string, d fs.DirEntry, icons IconSet) string {
+	ext, key := c.MatchEntryKey(path, d)
+	icon := icons.icon(name, key, d.Type())
+	if icon == "" {
+		return ext.Format(name)
+	}
+	ic := ext
+	if icons.Color.Seq != "" {
+		ic = &icons.Color
+	}
+	b := make([]byte, 0, ic.FormattedLen(icon)+1+ext.FormattedLen(name))
+	b = ic.AppendFormat(b, icon)
+	b = append(b, ' ')
+	return string(ext.AppendFormat(b, name))
+}
@@ -0,0 +1,48 @@
+package lscolors
+
+import (
+	"os"
+	"testing"
+)
+
+func TestFormatWithIcon(t *testing.T) {
+	ls, err := ParseLSColors("di=01;34:ex=01;32:*.go=32:*.md=33")
+	if err != nil {
+		t.Fatal(err)
+	}
+	icons := IconSet{
+		Icons: map[string]string{
+			"di":   "D",
+			"*.go": "G",
+			"*.sh": "S",
+		},
+		Default: "F",
+	}
+	tests := []struct {
+		entry testDirEntry
+		want  string
+	}{
+		{testDirEntry{name: "src", mode: os.ModeDir}, "\x1b[01;34mD\x1b[0m \x1b[01;34msrc\x1b[0m"},
+		{testDirEntry{name: "main.go"}, "\x1b[32mG\x1b[0m \x1b[32mmain.go\x1b[0m"},
+		// The extension is used when the key of the rule has no icon
+		{testDirEntry{name: "build.sh", mode: 0755}, "\x1b[01;32mS\x1b[0m \x1b[01;32mbuild.sh\x1b[0m"},
+		{testDirEntry{name: "README.md"}, "\x1b[33mF\x1b[0m \x1b[33mREADME.md\x1b[0m"},
+		{testDirEntry{name: "file"}, "F file"},
+	}
+	for _, x := range tests {
+		if got := ls.FormatWithIcon(x.entry.name, x.entry.name, x.entry, icons); got != x.want {
+			t.Errorf("FormatWithIcon(%q) = %q; want: %q", x.entry.name, got, x.want)
+		}
+	}
+
+	// Icons with their own color and no default
+	icons.Color = ColorExtension{Seq: "90"}
+	icons.Default = ""
+	if got, want := ls.FormatWithIcon("main.go", "main.go", testDirEntry{name: "main.go"}, icons),
+		"\x1b[90mG\x1b[0m \x1b[32mmain.go\x1b[0m"; got != want {
+		t.Errorf("FormatWithIcon = %q; want: %q", got, want)
+	}
+	if got, want := ls.FormatWithIcon("a.md", "a.md", testDirEntry{name: "a.md"}, icons), "\x1b[33ma.md\x1b[0m"; got != want {
+		t.Errorf("FormatWithIcon = %q; want: %q", got, want)
+	}
+}
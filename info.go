@@ -0,0 +1,125 @@
+package lscolors
+
+import (
+	"io/fs"
+	"time"
+)
+
+// A SizeRule colors regular files that are at least Min bytes in size.
+type SizeRule struct {
+	Min int64
+	ColorExtension
+}
+
+// An AgeRule colors regular files by the time since they were modified.
+// It matches files modified within Age or, if Older is set, files that
+// were last modified more than Age ago.
+type AgeRule struct {
+	Age   time.Duration
+	Older bool
+	ColorExtension
+}
+
+// A DeviceRule colors the block or character devices with the major device
+// number Major, such as 4 for TTYs or 8 for SCSI disks on Linux.
+type DeviceRule struct {
+	Major uint32
+	Block bool // match block devices instead of character devices
+	ColorExtension
+}
+
+// matchInfoRules returns the color of the first optional FileInfo based
+// rule that matches fi or nil if none do.
+func (c *LSColors) matchInfoRules(fi fs.FileInfo) *ColorExtension {
+	if !c.WhiteoutColor.Empty() && isWhiteout(fi) {
+		return &c.WhiteoutColor
+	}
+	if len(c.DeviceRules) != 0 && fi.Mode()&fs.ModeDevice != 0 {
+		if e := c.matchDevice(fi); e != nil {
+			return e
+		}
+	}
+	if len(c.SizeRules) != 0 && fi.Mode().IsRegular() {
+		if e := c.matchSize(fi.Size()); e != nil {
+			return e
+		}
+	}
+	if len(c.AgeRules) != 0 && fi.Mode().IsRegular() {
+		if e := c.matchAge(fi.ModTime()); e != nil {
+			return e
+		}
+	}
+	if (!c.OwnedColor.Empty() || !c.OtherColor.Empty()) && fi.Mode().IsRegular() {
+		if e := c.matchOwner(fi); e != nil {
+			return e
+		}
+	}
+	return nil
+}
+
+// matchDevice returns the first DeviceRule that matches the device fi or
+// nil if none do or its device number is unknown.
+func (c *LSColors) matchDevice(fi fs.FileInfo) *ColorExtension {
+	major, ok := deviceMajor(fi)
+	if !ok {
+		return nil
+	}
+	block := fi.Mode()&fs.ModeCharDevice == 0
+	for i := range c.DeviceRules {
+		r := &c.DeviceRules[i]
+		if r.Major == major && r.Block == block {
+			return &r.ColorExtension
+		}
+	}
+	return nil
+}
+
+// matchOwner returns OwnedColor or OtherColor, depending on who owns fi, or
+// nil if the color is not set or the owner is unknown.
+func (c *LSColors) matchOwner(fi fs.FileInfo) *ColorExtension {
+	owned, ok := ownedByCurrentUser(fi)
+	switch {
+	case !ok:
+		return nil
+	case owned && !c.OwnedColor.Empty():
+		return &c.OwnedColor
+	case !owned && !c.OtherColor.Empty():
+		return &c.OtherColor
+	}
+	return nil
+}
+
+// now returns the current time using Now, if set.
+func (c *LSColors) now() time.Time {
+	if c.Now != nil {
+		return c.Now()
+	}
+	return time.Now()
+}
+
+// matchAge returns the first rule that matches a file modified at mtime.
+func (c *LSColors) matchAge(mtime time.Time) *ColorExtension {
+	age := c.now().Sub(mtime)
+	for i := range c.AgeRules {
+		r := &c.AgeRules[i]
+		if (age > r.Age) == r.Older {
+			return &r.ColorExtension
+		}
+	}
+	return nil
+}
+
+// matchSize returns the rule with the largest Min that size satisfies.
+func (c *LSColors) matchSize(size int64) *ColorExtension {
+	var match *SizeRule
+	for i := range c.SizeRules {
+		r := &c.SizeRules[i]
+		if size >= r.Min && (match == nil || r.Min > match.Min) {
+			match = r
+		}
+	}
+	if match == nil {
+		return nil
+	}
+	return &match.ColorExtension
+}
@@ -0,0 +1,115 @@
+package lscolors
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSizeRules(t *testing.T) {
+	dir := t.TempDir()
+	sizes := map[string]int{
+		"empty.go": 0,
+		"small.go": 100,
+		"big.go":   2048,
+		"huge.go":  1 << 20,
+	}
+	for name, size := range sizes {
+		if err := os.WriteFile(filepath.Join(dir, name), make([]byte, size), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ls, err := ParseLSColors("di=01;34:*.go=32")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ls.SizeRules = []SizeRule{
+		{Min: 1 << 20, ColorExtension: ColorExtension{Seq: "01;31"}},
+		{Min: 1024, ColorExtension: ColorExtension{Seq: "33"}},
+	}
+	tests := map[string]string{
+		"empty.go": "32",
+		"small.go": "32",
+		"big.go":   "33",
+		"huge.go":  "01;31",
+	}
+	for name, want := range tests {
+		path := filepath.Join(dir, name)
+		fi, err := os.Lstat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if e := ls.MatchInfo(path, fi); e.Seq != want {
+			t.Errorf("MatchInfo(%q) = %q; want: %q", name, e.Seq, want)
+		}
+	}
+
+	// Directories are never matched by size
+	ls.SizeRules = []SizeRule{{Min: 0, ColorExtension: ColorExtension{Seq: "33"}}}
+	fi, err := os.Lstat(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e := ls.MatchInfo(dir, fi); e != &ls.DI {
+		t.Errorf("MatchInfo(dir) = %+v; want: %+v", e, ls.DI)
+	}
+}
+
+func TestAgeRules(t *testing.T) {
+	now := time.Date(2024, 1, 2, 12, 0, 0, 0, time.UTC)
+	dir := t.TempDir()
+	ages := map[string]time.Duration{
+		"new.log":   time.Minute,
+		"today.log": 6 * time.Hour,
+		"week.log":  3 * 24 * time.Hour,
+		"old.log":   90 * 24 * time.Hour,
+	}
+	for name, age := range ages {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+		mtime := now.Add(-age)
+		if err := os.Chtimes(path, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ls, err := ParseLSColors("*.log=37")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ls.Now = func() time.Time { return now }
+	ls.AgeRules = []AgeRule{
+		{Age: time.Hour, ColorExtension: ColorExtension{Seq: "01;32"}},
+		{Age: 24 * time.Hour, ColorExtension: ColorExtension{Seq: "32"}},
+		{Age: 30 * 24 * time.Hour, Older: true, ColorExtension: ColorExtension{Seq: "02"}},
+	}
+	tests := map[string]string{
+		"new.log":   "01;32",
+		"today.log": "32",
+		"week.log":  "37",
+		"old.log":   "02",
+	}
+	for name, want := range tests {
+		path := filepath.Join(dir, name)
+		fi, err := os.Lstat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if e := ls.MatchInfo(path, fi); e.Seq != want {
+			t.Errorf("MatchInfo(%q) = %q; want: %q", name, e.Seq, want)
+		}
+	}
+
+	// Size rules take precedence
+	ls.SizeRules = []SizeRule{{Min: 0, ColorExtension: ColorExtension{Seq: "31"}}}
+	path := filepath.Join(dir, "new.log")
+	fi, err := os.Lstat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e := ls.MatchInfo(path, fi); e.Seq != "31" {
+		t.Errorf("MatchInfo(%q) = %q; want: %q", path, e.Seq, "31")
+	}
+}
@@ -0,0 +1,13 @@
+//go:build go1.23
+
+package lscolors
+
+import "iter"
+
+// All returns an iterator over the key and sequence of every rule that is
+// set. The order is the same as Range.
+func (c *LSColors) All() iter.Seq2[string, string] {
+	return func(yield func(key, seq string) bool) {
+		c.Range(yield)
+	}
+}
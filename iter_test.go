@@ -0,0 +1,40 @@
+//go:build go1.23
+
+package lscolors
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAll(t *testing.T) {
+	ls, err := ParseLSColors("*.go=32:ln=01;36:di=01;34:*.c=33:.git*=1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got [][2]string
+	for key, seq := range ls.All() {
+		got = append(got, [2]string{key, seq})
+	}
+	want := [][2]string{
+		{"di", "01;34"},
+		{"ln", "01;36"},
+		{"*.c", "33"},
+		{"*.go", "32"},
+		{".git*", "1"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("All() = %q; want: %q", got, want)
+	}
+
+	got = got[:0]
+	for key, seq := range ls.All() {
+		got = append(got, [2]string{key, seq})
+		if key == "*.c" {
+			break
+		}
+	}
+	if !reflect.DeepEqual(got, want[:3]) {
+		t.Errorf("All() with break = %q; want: %q", got, want[:3])
+	}
+}
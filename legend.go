@@ -0,0 +1,54 @@
+package lscolors
+
+import "strings"
+
+// legendLabels are the sample labels used by Legend for the indicators
+// returned by LSColors.indicators, in the same order. RS is omitted since
+// it is a reset and not a color.
+var legendLabels = [...]string{
+	"directory", "file", "symlink", "pipe", "socket",
+	"block device", "char device", "orphan", "missing", "executable",
+	"sticky other-writable", "normal", "sticky", "other-writable", "",
+}
+
+// legendExts is the maximum number of extensions shown by Legend.
+const legendExts = 8
+
+// Legend returns a sample of the palette to preview it: one line for each
+// indicator that is set, such as "di  directory", followed by the first
+// few extensions in declaration order, such as "*.go  main.go", each with
+// its label colored using Format.
+func (c *LSColors) Legend() string {
+	type entry struct {
+		key, label string
+		e          *ColorExtension
+	}
+	var entries []entry
+	for i, e := range c.indicators() {
+		if e.Seq != "" && legendLabels[i] != "" {
+			entries = append(entries, entry{indicatorKeys[i], legendLabels[i], e})
+		}
+	}
+	exts := c.ExtensionsInOrder()
+	for i := 0; i < len(exts) && i < legendExts; i++ {
+		e := &exts[i]
+		label := e.Ext
+		if strings.HasPrefix(label, ".") {
+			label = "file" + label
+		}
+		entries = append(entries, entry{"*" + e.Ext, label, e})
+	}
+
+	width := 0
+	for _, x := range entries {
+		width = max(width, len(x.key))
+	}
+	var w strings.Builder
+	for _, x := range entries {
+		w.WriteString(x.key)
+		w.WriteString(strings.Repeat(" ", width-len(x.key)+2))
+		w.WriteString(x.e.Format(x.label))
+		w.WriteByte('\n')
+	}
+	return w.String()
+}
@@ -0,0 +1,56 @@
+package lscolors
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestLegend(t *testing.T) {
+	ls, err := ParseLSColors("rs=0:di=01;34:ln=01;36:ex=01;32:*.go=32:*README=33:.git*=1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "di       \x1b[01;34mdirectory\x1b[0m\n" +
+		"ln       \x1b[01;36msymlink\x1b[0m\n" +
+		"ex       \x1b[01;32mexecutable\x1b[0m\n" +
+		"*.go     \x1b[32mfile.go\x1b[0m\n" +
+		"*README  \x1b[33mREADME\x1b[0m\n"
+	if got := ls.Legend(); got != want {
+		t.Errorf("Legend:\ngot:  %q\nwant: %q", got, want)
+	}
+}
+
+func TestLegendIndicators(t *testing.T) {
+	ls := DefaultLSColors()
+	var exts []string
+	for i := 0; i < 20; i++ {
+		exts = append(exts, fmt.Sprintf("*.e%d=3%d", i, i%8))
+	}
+	more, err := ParseLSColors(strings.Join(exts, ":"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ls.AddExtensions(more.Exts)
+
+	legend := ls.Legend()
+	lines := strings.Split(strings.TrimSuffix(legend, "\n"), "\n")
+	n := 0
+	for i, e := range ls.indicators() {
+		if e.Seq == "" || indicatorKeys[i] == "rs" {
+			continue
+		}
+		label := e.Format(legendLabels[i])
+		if n >= len(lines) || !strings.HasPrefix(lines[n], indicatorKeys[i]+" ") ||
+			!strings.HasSuffix(lines[n], " "+label) {
+			t.Errorf("missing indicator %q (%q) in legend:\n%s", indicatorKeys[i], label, legend)
+		}
+		n++
+	}
+	if len(lines) != n+legendExts {
+		t.Errorf("legend has %d lines; want: %d", len(lines), n+legendExts)
+	}
+	if strings.Contains(legend, "rs ") {
+		t.Errorf("legend contains rs:\n%s", legend)
+	}
+}
@@ -0,0 +1,246 @@
+package lscolors
+
+import "strconv"
+
+// A ColorLevel is the number of colors supported by a terminal.
+type ColorLevel int
+
+const (
+	LevelNone      ColorLevel = iota // No color support
+	Level16                          // 16 colors (SGR 30-37, 40-47, 90-97, 100-107)
+	Level256                         // 256 colors (SGR 38;5;n and 48;5;n)
+	LevelTrueColor                   // 24-bit color (SGR 38;2;r;g;b and 48;2;r;g;b)
+)
+
+func (l ColorLevel) String() string {
+	switch l {
+	case LevelNone:
+		return "none"
+	case Level16:
+		return "16"
+	case Level256:
+		return "256"
+	case LevelTrueColor:
+		return "truecolor"
+	}
+	return "ColorLevel(" + strconv.Itoa(int(l)) + ")"
+}
+
+// xterm's default values for the 16 standard colors.
+var ansi16 = [16][3]uint8{
+	{0, 0, 0}, {205, 0, 0}, {0, 205, 0}, {205, 205, 0},
+	{0, 0, 238}, {205, 0, 205}, {0, 205, 205}, {229, 229, 229},
+	{127, 127, 127}, {255, 0, 0}, {0, 255, 0}, {255, 255, 0},
+	{92, 92, 255}, {255, 0, 255}, {0, 255, 255}, {255, 255, 255},
+}
+
+// Levels of the 6x6x6 color cube of the 256 color palette.
+var cubeLevels = [6]uint8{0, 95, 135, 175, 215, 255}
+
+// color256ToRGB returns the RGB value of the 256 color palette index n.
+func color256ToRGB(n int) [3]uint8 {
+	switch {
+	case n < 16:
+		return ansi16[n]
+	case n < 232:
+		n -= 16
+		return [3]uint8{cubeLevels[n/36], cubeLevels[n/6%6], cubeLevels[n%6]}
+	default:
+		v := uint8(8 + 10*(n-232))
+		return [3]uint8{v, v, v}
+	}
+}
+
+func colorDist(a, b [3]uint8) int {
+	dr := int(a[0]) - int(b[0])
+	dg := int(a[1]) - int(b[1])
+	db := int(a[2]) - int(b[2])
+	return dr*dr + dg*dg + db*db
+}
+
+// rgbTo256 returns the index of the 256 color palette entry (excluding the
+// 16 standard colors, which vary between terminals) closest to rgb.
+func rgbTo256(rgb [3]uint8) int {
+	cube := func(v uint8) int {
+		for i := 0; i < len(cubeLevels)-1; i++ {
+			if int(v) < (int(cubeLevels[i])+int(cubeLevels[i+1]))/2 {
+				return i
+			}
+		}
+		return len(cubeLevels) - 1
+	}
+	r, g, b := cube(rgb[0]), cube(rgb[1]), cube(rgb[2])
+	ci := 16 + 36*r + 6*g + b
+
+	avg := (int(rgb[0]) + int(rgb[1]) + int(rgb[2])) / 3
+	gi := 232 + min(max((avg-8+5)/10, 0), 23)
+
+	if colorDist(color256ToRGB(gi), rgb) < colorDist(color256ToRGB(ci), rgb) {
+		return gi
+	}
+	return ci
+}
+
+// rgbTo16 returns the index (0-15) of the standard color closest to rgb.
+func rgbTo16(rgb [3]uint8) int {
+	best, bestDist := 0, -1
+	for i, c := range ansi16 {
+		if d := colorDist(c, rgb); bestDist < 0 || d < bestDist {
+			best, bestDist = i, d
+		}
+	}
+	return best
+}
+
+// sgr16 returns the SGR code for the standard color n (0-15) as a
+// foreground or background color.
+func sgr16(n int, bg bool) int {
+	code := 30 + n
+	if n >= 8 {
+		code = 90 + n - 8
+	}
+	if bg {
+		code += 10
+	}
+	return code
+}
+
+// downsample rewrites seq so that it only uses colors supported by level.
+// Invalid sequences are returned unmodified.
+func downsample(seq string, level ColorLevel) string {
+	if level >= LevelTrueColor {
+		return seq
+	}
+	params, ok := parseSGR(seq)
+	if !ok {
+		return seq
+	}
+	out := make([]int, 0, len(params))
+	changed := false
+	for i := 0; i < len(params); i++ {
+		p := params[i]
+		switch {
+		case p == 38 || p == 48:
+			changed = true
+			bg := p == 48
+			var rgb [3]uint8
+			var idx int
+			switch {
+			case i+2 < len(params) && params[i+1] == 5:
+				idx = params[i+2]
+				rgb = color256ToRGB(idx)
+				i += 2
+				if level == Level256 {
+					out = append(out, p, 5, idx)
+					continue
+				}
+				if idx < 16 {
+					if level == Level16 {
+						out = append(out, sgr16(idx, bg))
+					}
+					continue
+				}
+			case i+4 < len(params) && params[i+1] == 2:
+				rgb = [3]uint8{uint8(params[i+2]), uint8(params[i+3]), uint8(params[i+4])}
+				i += 4
+				if level == Level256 {
+					out = append(out, p, 5, rgbTo256(rgb))
+					continue
+				}
+			default:
+				i = len(params) // malformed: drop the rest
+				continue
+			}
+			if level == Level16 {
+				out = append(out, sgr16(rgbTo16(rgb), bg))
+			}
+		case 30 <= p && p <= 37, 40 <= p && p <= 47, 90 <= p && p <= 97, 100 <= p && p <= 107:
+			if level > LevelNone {
+				out = append(out, p)
+			} else {
+				changed = true
+			}
+		default:
+			out = append(out, p)
+		}
+	}
+	if !changed {
+		return seq
+	}
+	b := make([]byte, 0, len(seq))
+	for i, p := range out {
+		if i > 0 {
+			b = append(b, ';')
+		}
+		b = strconv.AppendInt(b, int64(p), 10)
+	}
+	return string(b)
+}
+
+// ColorDepth returns the lowest ColorLevel that supports all of the colors
+// used by the SGR sequence seq: LevelTrueColor if it uses a 24-bit color
+// (38;2 or 48;2), Level256 if it uses the 256 color palette (38;5 or 48;5),
+// Level16 if it uses one of the 16 standard colors and LevelNone if it
+// only sets attributes, such as bold, or is invalid.
+func ColorDepth(seq string) ColorLevel {
+	params, ok := parseSGR(seq)
+	if !ok {
+		return LevelNone
+	}
+	level := LevelNone
+	for i := 0; i < len(params); i++ {
+		switch p := params[i]; {
+		case p == 38 || p == 48:
+			switch {
+			case i+2 < len(params) && params[i+1] == 5:
+				level = max(level, Level256)
+				i += 2
+			case i+4 < len(params) && params[i+1] == 2:
+				return LevelTrueColor
+			default:
+				return level // malformed: ignore the rest
+			}
+		case 30 <= p && p <= 37, 40 <= p && p <= 47, 90 <= p && p <= 97, 100 <= p && p <= 107:
+			level = max(level, Level16)
+		}
+	}
+	return level
+}
+
+// RequiredColorLevel returns the ColorLevel needed to display every
+// indicator, extension and glob sequence of c, which is the maximum
+// ColorDepth of the sequences. It can be compared with the level supported
+// by the terminal to warn about palettes that will be downsampled.
+func (c *LSColors) RequiredColorLevel() ColorLevel {
+	level := LevelNone
+	c.eachRule(func(e *ColorExtension) {
+		if level < LevelTrueColor && e.Seq != "" {
+			level = max(level, ColorDepth(e.Seq))
+		}
+	})
+	return level
+}
+
+// FormatLevel is like Format but first converts the color sequence to only
+// use colors supported by level. Truecolor values are mapped to the nearest
+// entry of the 256 color palette or the 16 standard colors. If level is
+// LevelNone s is returned without any escape sequences. The palette is not
+// modified so it may be shared by terminals with different capabilities.
+//
+// The sequence is converted on every call. The result is not cached since
+// ColorExtension has no room for it without breaking unkeyed literals and
+// comparisons, and a package level cache would grow without bound and
+// outlive the palettes that filled it.
+func (c *ColorExtension) FormatLevel(s string, level ColorLevel) string {
+	if level <= LevelNone {
+		return s
+	}
+	if level >= LevelTrueColor || c.Seq == "" {
+		return c.Format(s)
+	}
+	seq := downsample(c.Seq, level)
+	if seq == "" {
+		return s
+	}
+	return "\x1b[" + seq + "m" + s + resetSeq
+}
@@ -0,0 +1,127 @@
+package lscolors
+
+import "testing"
+
+func TestFormatLevel(t *testing.T) {
+	const seq = "1;38;2;129;162;190;48;2;255;0;0"
+	tests := []struct {
+		level ColorLevel
+		want  string
+	}{
+		{LevelTrueColor, "\x1b[" + seq + "mx\x1b[0m"},
+		{Level256, "\x1b[1;38;5;109;48;5;196mx\x1b[0m"},
+		{Level16, "\x1b[1;90;101mx\x1b[0m"},
+		{LevelNone, "x"},
+	}
+	e := ColorExtension{Ext: "di", Seq: seq}
+	for _, x := range tests {
+		for i := 0; i < 2; i++ { // second iteration is cached
+			if got := e.FormatLevel("x", x.level); got != x.want {
+				t.Errorf("FormatLevel(%s) = %q; want: %q", x.level, got, x.want)
+			}
+		}
+	}
+	if e.Seq != seq {
+		t.Errorf("FormatLevel modified the sequence: %q", e.Seq)
+	}
+}
+
+func TestDownsample(t *testing.T) {
+	tests := []struct {
+		seq   string
+		level ColorLevel
+		want  string
+	}{
+		{"01;34", Level16, "01;34"},
+		{"01;34", LevelNone, "1"},
+		{"38;5;9", Level16, "91"},
+		{"48;5;4", Level16, "44"},
+		{"38;5;231", Level16, "97"},
+		{"38;5;232", Level256, "38;5;232"},
+		{"38;2;8;8;8", Level256, "38;5;232"},
+		{"38;2;255;255;255", Level256, "38;5;231"},
+		{"4;38;2;0;0;0", Level16, "4;30"},
+		{"38;2;1", Level256, ""},
+	}
+	for _, x := range tests {
+		if got := downsample(x.seq, x.level); got != x.want {
+			t.Errorf("downsample(%q, %s) = %q; want: %q", x.seq, x.level, got, x.want)
+		}
+	}
+}
+
+func TestFormatLevelBackgroundOnly(t *testing.T) {
+	tests := []struct {
+		seq   string
+		level ColorLevel
+		want  string
+	}{
+		{"4", Level16, "4"},
+		{"7", LevelTrueColor, "7"},
+		{"48;5;234", Level256, "48;5;234"},
+		{"48;5;234", Level16, "40"},
+		{"48;2;30;30;30", Level256, "48;5;234"},
+	}
+	for _, x := range tests {
+		if !validSequence(x.seq) {
+			t.Errorf("validSequence(%q) = false", x.seq)
+		}
+		e := ColorExtension{Seq: x.seq}
+		want := "\x1b[" + x.want + "mx\x1b[0m"
+		if got := e.FormatLevel("x", x.level); got != want {
+			t.Errorf("FormatLevel(%q, %s) = %q; want: %q", x.seq, x.level, got, want)
+		}
+	}
+}
+
+func TestColorDepth(t *testing.T) {
+	tests := []struct {
+		seq  string
+		want ColorLevel
+	}{
+		{"", LevelNone},
+		{"01", LevelNone},
+		{"01;04;39", LevelNone},
+		{"01;34", Level16},
+		{"30;43", Level16},
+		{"93", Level16},
+		{"01;104", Level16},
+		{"38;5;208", Level256},
+		{"34;48;5;4", Level256},
+		{"38;2;255;128;0", LevelTrueColor},
+		{"38;5;1;48;2;1;2;3", LevelTrueColor},
+		{"01;38;5", LevelNone}, // malformed
+		{"bad", LevelNone},
+	}
+	for _, x := range tests {
+		if got := ColorDepth(x.seq); got != x.want {
+			t.Errorf("ColorDepth(%q) = %s; want: %s", x.seq, got, x.want)
+		}
+	}
+}
+
+func TestRequiredColorLevel(t *testing.T) {
+	tests := []struct {
+		colors string
+		want   ColorLevel
+	}{
+		{"di=01:ln=04", LevelNone},
+		{"di=01;34:ln=01;36:*.go=32", Level16},
+		{"di=01;34:*.go=38;5;208:*.c=33", Level256},
+		{"di=01;34:*.go=38;5;208:*README=38;2;255;128;0", LevelTrueColor},
+		{"di=48;2;0;0;0:*.go=38;5;208", LevelTrueColor},
+	}
+	for _, x := range tests {
+		ls, err := ParseLSColors(x.colors)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := ls.RequiredColorLevel(); got != x.want {
+			t.Errorf("%q: RequiredColorLevel() = %s; want: %s", x.colors, got, x.want)
+		}
+	}
+	var ls LSColors
+	if got := ls.RequiredColorLevel(); got != LevelNone {
+		t.Errorf("RequiredColorLevel() = %s; want: %s", got, LevelNone)
+	}
+}
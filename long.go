@@ -0,0 +1,65 @@
+package lscolors
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// FormatLong returns an ls -l style line for the file at path name with
+// FileInfo info: its mode, size and modification time, which are not
+// colored, followed by name colored by the type of the file. Like ls, the
+// time includes the year instead of the time of day for files modified
+// more than six months ago or in the future (see Now).
+//
+// Symbolic links are followed by " -> " and their target, which is colored
+// by the type of the file it names or, if it cannot be stat'd, by MI (or OR
+// if MI is not set).
+func (c *LSColors) FormatLong(info fs.FileInfo, name string) string {
+	var b []byte
+	b = append(b, info.Mode().String()...)
+	b = append(b, ' ')
+	size := strconv.FormatInt(info.Size(), 10)
+	for i := len(size); i < 8; i++ {
+		b = append(b, ' ')
+	}
+	b = append(b, size...)
+	b = append(b, ' ')
+	b = append(b, c.longTime(info.ModTime())...)
+	b = append(b, ' ')
+	b = c.MatchInfo(name, info).AppendFormat(b, name)
+	if info.Mode()&fs.ModeSymlink != 0 {
+		if target, err := os.Readlink(name); err == nil {
+			b = append(b, " -> "...)
+			b = c.linkTarget(name, target).AppendFormat(b, target)
+		}
+	}
+	return string(b)
+}
+
+// longTime formats t like ls -l does.
+func (c *LSColors) longTime(t time.Time) string {
+	const halfYear = 365 * 24 * time.Hour / 2
+	if age := c.now().Sub(t); age < 0 || age > halfYear {
+		return t.Format("Jan _2  2006")
+	}
+	return t.Format("Jan _2 15:04")
+}
+
+// linkTarget returns the color of target, the target of the symbolic link
+// at path.
+func (c *LSColors) linkTarget(path, target string) *ColorExtension {
+	if !filepath.IsAbs(target) {
+		target = filepath.Join(filepath.Dir(path), target)
+	}
+	fi, err := os.Stat(target)
+	if err != nil {
+		if !c.MI.Empty() {
+			return &c.MI
+		}
+		return &c.OR
+	}
+	return c.MatchInfo(target, fi)
+}
@@ -0,0 +1,112 @@
+package lscolors
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+type longInfo struct {
+	name  string
+	size  int64
+	mode  fs.FileMode
+	mtime time.Time
+}
+
+func (fi longInfo) Name() string       { return fi.name }
+func (fi longInfo) Size() int64        { return fi.size }
+func (fi longInfo) Mode() fs.FileMode  { return fi.mode }
+func (fi longInfo) ModTime() time.Time { return fi.mtime }
+func (fi longInfo) IsDir() bool        { return fi.mode.IsDir() }
+func (fi longInfo) Sys() any           { return nil }
+
+func TestFormatLong(t *testing.T) {
+	ls, err := ParseLSColors("di=01;34:ln=01;36:or=31:mi=05;31:*.go=32")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mtime := time.Date(2024, time.March, 5, 14, 7, 0, 0, time.UTC)
+	ls.Now = func() time.Time { return mtime.Add(24 * time.Hour) }
+
+	tests := []struct {
+		fi   longInfo
+		want string
+	}{
+		{
+			longInfo{"main.go", 1234, 0644, mtime},
+			"-rw-r--r--     1234 Mar  5 14:07 \x1b[32mmain.go\x1b[0m",
+		},
+		{
+			longInfo{"src", 4096, fs.ModeDir | 0755, mtime},
+			"drwxr-xr-x     4096 Mar  5 14:07 \x1b[01;34msrc\x1b[0m",
+		},
+		{
+			longInfo{"old.go", 123456789, 0600, mtime.AddDate(-1, 0, 0)},
+			"-rw------- 123456789 Mar  5  2023 \x1b[32mold.go\x1b[0m",
+		},
+		{
+			longInfo{"future.go", 0, 0644, mtime.AddDate(0, 0, 2)},
+			"-rw-r--r--        0 Mar  7  2024 \x1b[32mfuture.go\x1b[0m",
+		},
+	}
+	for _, x := range tests {
+		if got := ls.FormatLong(x.fi, x.fi.name); got != x.want {
+			t.Errorf("FormatLong(%q):\ngot:  %q\nwant: %q", x.fi.name, got, x.want)
+		}
+	}
+}
+
+func TestFormatLongSymlink(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "main.go"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	link := filepath.Join(dir, "link")
+	if err := os.Symlink("main.go", link); err != nil {
+		t.Skip(err)
+	}
+	broken := filepath.Join(dir, "broken")
+	if err := os.Symlink("missing.go", broken); err != nil {
+		t.Fatal(err)
+	}
+	ls, err := ParseLSColors("di=01;34:ln=01;36:or=31:mi=05;31:*.go=32")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		path, want string
+	}{
+		{link, "\x1b[01;36m" + link + "\x1b[0m -> \x1b[32mmain.go\x1b[0m"},
+		{broken, "\x1b[31m" + broken + "\x1b[0m -> \x1b[05;31mmissing.go\x1b[0m"},
+	}
+	for _, x := range tests {
+		fi, err := os.Lstat(x.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := fi.Mode().String() + " " + padLeft(fi.Size()) + " " +
+			ls.longTime(fi.ModTime()) + " " + x.want
+		if got := ls.FormatLong(fi, x.path); got != want {
+			t.Errorf("FormatLong(%q):\ngot:  %q\nwant: %q", x.path, got, want)
+		}
+	}
+
+	// The target falls back to OR if MI is not set
+	ls.MI = ColorExtension{}
+	fi, err := os.Lstat(broken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "\x1b[31m" + broken + "\x1b[0m -> \x1b[31mmissing.go\x1b[0m"
+	if got := ls.FormatLong(fi, broken); !strings.HasSuffix(got, want) {
+		t.Errorf("FormatLong(%q) = %q; want suffix: %q", broken, got, want)
+	}
+}
+
+func padLeft(n int64) string {
+	return fmt.Sprintf("%8d", n)
+}
@@ -4,10 +4,16 @@ import (
 	"errors"
 	"fmt"
 	"io/fs"
+	"maps"
 	"os"
+	"path"
+	"path/filepath"
 	"slices"
-	"sort"
+	"strconv"
 	"strings"
+	"time"
+	"unicode/utf8"
+	"unsafe"
 )
 
 type ParseError struct {
@@ -18,6 +24,14 @@ func (e *ParseError) Error() string {
 	return fmt.Sprintf("lscolors: unparsable value for LS_COLORS value: %q", e.Value)
 }
 
+var (
+	// ErrNotSet is returned by NewLSColors when LS_COLORS is not set.
+	ErrNotSet = errors.New("ls_colors: LS_COLORS not set")
+
+	// ErrEmpty is returned when parsing an empty LS_COLORS value.
+	ErrEmpty = errors.New("ls_colors: empty LS_COLORS argument")
+)
+
 var NoColor ColorExtension
 
 type ColorExtension struct {
@@ -35,31 +49,175 @@ func (c *ColorExtension) MatchExt(name string) bool {
 	// Fast test for last char to skip strcmp when possible.
 	// This yields a ~6x speed up.
 	return i > 0 && j > 0 /* BCE */ && j <= i && name[i-1] == c.Ext[j-1] &&
-		strings.HasSuffix(name, c.Ext)
+		strings.HasSuffix(name, c.Ext) &&
+		// Don't match in the middle of a multibyte rune, which can
+		// only happen if Ext starts with a UTF-8 continuation byte.
+		(c.Ext[0] < utf8.RuneSelf || runeBoundary(name, i-j))
+}
+
+// runeBoundary reports if s[i] is not in the middle of a valid multibyte
+// rune.
+func runeBoundary(s string, i int) bool {
+	if i == 0 || utf8.RuneStart(s[i]) {
+		return true
+	}
+	j := i - 1
+	for j > 0 && j > i-utf8.UTFMax && !utf8.RuneStart(s[j]) {
+		j--
+	}
+	r, n := utf8.DecodeRuneInString(s[j:])
+	return r == utf8.RuneError && n == 1 || j+n <= i
+}
+
+// resetSeq is emitted after every colored string. It is always a full reset
+// (even if "rs" is customized) so that attributes such as underline and
+// italic can never bleed into the text that follows.
+const resetSeq = "\x1b[0m"
+
+// FormattedLen returns the length of Format(s) without formatting s. It is
+// useful for sizing buffers before formatting many names.
+func (c *ColorExtension) FormattedLen(s string) int {
+	if c.Seq == "" {
+		return len(s)
+	}
+	return len("\x1b[") + len(c.Seq) + len("m") + len(s) + len(resetSeq)
 }
 
+// AppendFormat appends s colored by c to b and returns the extended buffer.
+// If c has no sequence, such as NoColor, s is appended without any escape
+// sequences.
 func (c *ColorExtension) AppendFormat(b []byte, s string) []byte {
 	if c.Seq == "" {
-		b = slices.Grow(b, len("\x1b[0m")+len(s)+len("\x1b[0m"))
-		b = append(b, "\x1b[0m"...)
-		b = append(b, s...)
-		b = append(b, "\x1b[0m"...)
-		return b
+		return append(b, s...)
 	}
-	b = slices.Grow(b, len("\x1b[")+len(c.Seq)+len("m")+len(s)+len("\x1b[0m"))
+	b = slices.Grow(b, c.FormattedLen(s))
 	b = append(b, "\x1b["...)
 	b = append(b, c.Seq...)
 	b = append(b, 'm')
 	b = append(b, s...)
-	b = append(b, "\x1b[0m"...)
+	b = append(b, resetSeq...)
 	return b
 }
 
+// Format returns s colored by c. If c has no sequence, such as NoColor, s
+// is returned unmodified.
 func (c *ColorExtension) Format(s string) string {
 	if c.Seq == "" {
-		return "\x1b[0m" + s + "\x1b[0m" // TODO: do we need this?
+		return s
+	}
+	// The result is built with a single allocation so there is nothing to
+	// gain from caching the "\x1b[<seq>m" prefix, which would require a
+	// field that breaks unkeyed ColorExtension literals and comparisons.
+	return "\x1b[" + c.Seq + "m" + s + resetSeq
+}
+
+// WriteString writes s colored by c to sb. It is equivalent to
+// sb.WriteString(c.Format(s)) without allocating an intermediate string.
+func (c *ColorExtension) WriteString(sb *strings.Builder, s string) {
+	if c.Seq == "" {
+		sb.WriteString(s)
+		return
+	}
+	sb.Grow(c.FormattedLen(s))
+	sb.WriteString("\x1b[")
+	sb.WriteString(c.Seq)
+	sb.WriteByte('m')
+	sb.WriteString(s)
+	sb.WriteString(resetSeq)
+}
+
+// AppendFormatReset is like AppendFormat but if c has no sequence s is
+// surrounded by resets, which clears any colors left active by text that
+// was written before it.
+func (c *ColorExtension) AppendFormatReset(b []byte, s string) []byte {
+	if c.Seq == "" {
+		b = slices.Grow(b, len(resetSeq)+len(s)+len(resetSeq))
+		b = append(b, resetSeq...)
+		b = append(b, s...)
+		return append(b, resetSeq...)
+	}
+	return c.AppendFormat(b, s)
+}
+
+// FormatReset is like Format but if c has no sequence s is surrounded by
+// resets (see AppendFormatReset).
+func (c *ColorExtension) FormatReset(s string) string {
+	if c.Seq == "" {
+		return resetSeq + s + resetSeq
+	}
+	return c.Format(s)
+}
+
+// FormatKeepBackground is like Format but, instead of a full reset, only
+// resets the attributes and colors set by the sequence, such as "\x1b[22;39m"
+// for "01;34", so that a background painted by the caller (for example
+// to highlight a row) is preserved. If the sequence sets a background it is
+// reset to the default background, and a sequence that starts with a reset
+// ("0") will itself clear the background. If the sequence is empty s is
+// returned unmodified.
+func (c *ColorExtension) FormatKeepBackground(s string) string {
+	if c.Seq == "" {
+		return s
+	}
+	return "\x1b[" + c.Seq + "m" + s + partialReset(c.Seq)
+}
+
+// partialReset returns the sequence that undoes only the attributes and
+// colors set by the SGR sequence seq.
+func partialReset(seq string) string {
+	params, ok := parseSGR(seq)
+	if !ok {
+		return "\x1b[22;23;24;25;27;28;29;39m" // everything but the background
+	}
+	var codes []int
+	add := func(code int) {
+		if !slices.Contains(codes, code) {
+			codes = append(codes, code)
+		}
+	}
+	for i := 0; i < len(params); i++ {
+		switch p := params[i]; {
+		case p == 1 || p == 2:
+			add(22)
+		case p == 5 || p == 6:
+			add(25)
+		case 3 <= p && p <= 9:
+			add(20 + p)
+		case 30 <= p && p <= 37, 90 <= p && p <= 97:
+			add(39)
+		case 40 <= p && p <= 47, 100 <= p && p <= 107:
+			add(49)
+		case p == 38 || p == 48:
+			add(p + 1)
+			if i+1 < len(params) && params[i+1] == 5 {
+				i += 2
+			} else if i+1 < len(params) && params[i+1] == 2 {
+				i += 4
+			}
+		}
+	}
+	if len(codes) == 0 {
+		return ""
+	}
+	slices.Sort(codes)
+	b := append(make([]byte, 0, 16), "\x1b["...)
+	for i, code := range codes {
+		if i > 0 {
+			b = append(b, ';')
+		}
+		b = strconv.AppendInt(b, int64(code), 10)
+	}
+	return string(append(b, 'm'))
+}
+
+// FormatReverse is like Format but also enables reverse video (SGR 7), which
+// is useful for highlighting a selected entry. The attribute is added after
+// the sequence so that a leading reset ("00") does not clear it.
+func (c *ColorExtension) FormatReverse(s string) string {
+	if c.Seq == "" {
+		return "\x1b[7m" + s + resetSeq
 	}
-	return "\x1b[" + c.Seq + "m" + s + "\x1b[0m"
+	return "\x1b[" + c.Seq + ";7m" + s + resetSeq
 }
 
 // TODO: rename to ColorTerm or something more appropriate
@@ -136,6 +294,11 @@ static struct bin_str color_indicator[] = {
 };
 */
 
+// LSColors is a parsed LS_COLORS palette. Only the indicators (DI through
+// RS), Exts, Globs and the extension rules in Names are part of LS_COLORS
+// and serialized by String and MarshalBinary. The remaining fields, such
+// as SizeRules, HiddenColor and Git, extend the matching done by this
+// package and are not serialized.
 type LSColors struct {
 	DI ColorExtension // Directory
 	FI ColorExtension // File
@@ -155,28 +318,334 @@ type LSColors struct {
 	ST ColorExtension // sticky: black on blue
 	OW ColorExtension // other-writable: blue on green
 
+	// RS is the "reset to ordinary colors" sequence. It is parsed so that it
+	// round-trips but is not used when formatting: a full reset is always
+	// emitted since a customized RS may not clear every attribute.
+	RS ColorExtension
+
+	// LeftCode, RightCode and EndCode are the decoded "lc", "rc" and "ec"
+	// escape codes of LS_COLORS or the LEFTCODE, RIGHTCODE and ENDCODE
+	// codes of a dircolors database (see ParseDircolors). They are only
+	// used by Reset: formatting always uses standard SGR sequences. They
+	// are not serialized.
+	LeftCode  string
+	RightCode string
+	EndCode   string
+
 	Exts []ColorExtension
+
+	// Names maps exact file names, such as "Dockerfile", to the color used
+	// for them, whose Ext is the name. They take precedence over all other
+	// extension rules. ParseLSColors, Set and AddExtensions add an entry
+	// for each extension rule that does not start with a '.' (such as
+	// "*LICENSE"), which stays in Exts so it also matches as a suffix like
+	// GNU ls does. Names that are not also in Exts are not serialized.
+	Names map[string]*ColorExtension
+
+	// Globs are patterns that contain a '*' other than a leading one, such
+	// as "*.min.*" or ".git*". They are matched against the base name using
+	// path.Match and are only consulted when no suffix rule in Exts matches
+	// since they are considerably slower to match. The first matching glob,
+	// in declaration order, wins. Unlike Exts, Ext is the complete pattern.
+	Globs []ColorExtension
+
+	// ExtensionsBeatExecutable makes extension rules take precedence over EX
+	// for executable files so that, for example, an executable "build.sh"
+	// uses the "*.sh" color. By default EX wins, which is what GNU ls does:
+	// it only checks extensions for files not classified as executable.
+	// Executables without a matching extension rule still use EX. This
+	// applies to all of the matching methods.
+	//
+	// It is useful on network filesystems (NFS, SMB) that report every
+	// file as executable, since extension, name and glob rules then still
+	// apply to files such as "photo.jpg" that are not really executable.
+	ExtensionsBeatExecutable bool
+
+	// ScriptsBeatExecutable is like ExtensionsBeatExecutable but only
+	// applies to scripts: executables that start with a "#!" line, such as
+	// "build.sh", use their extension rule while binaries, such as
+	// "tool.bin", still use EX. Detecting scripts requires reading the
+	// first bytes of every executable, so it is off by default. Files are
+	// read from the FS passed to MatchFS or WalkDirFunc and otherwise from
+	// the operating system's filesystem.
+	ScriptsBeatExecutable bool
+
+	// MaxSuffixDepth limits extension and glob rules to the last
+	// MaxSuffixDepth dot separated segments of a file name, which bounds
+	// the work done for names with many dots. For example, with a depth
+	// of 2 only ".tar.gz" of "a.b.c.tar.gz" is matched, so "*.tar.gz" and
+	// "*.gz" match but "*.c.tar.gz" does not. Names with fewer dots are
+	// matched in full. Zero, the default, means no limit.
+	MaxSuffixDepth int
+
+	// FoldCase makes extension, name and glob rules ignore case, which is
+	// how names are compared by case-insensitive filesystems such as the
+	// default APFS and NTFS volumes: "IMG.JPG" then matches "*.jpg". The
+	// displayed name is not modified. If rules differ only by case the
+	// one that sorts last in Exts wins. It is off by default so that
+	// matching does not depend on the filesystem; DetectCaseInsensitive
+	// can be used to decide whether to set it.
+	FoldCase bool
+
+	// SocketsAsPipes colors sockets with PI when SO is not set. By default
+	// sockets and named pipes are kept distinct: a socket without an SO
+	// color falls back to NO, like every other special file, and is
+	// never colored as a pipe.
+	SocketsAsPipes bool
+
+	// FollowLinks colors symbolic links to directories with DI instead of
+	// LN, like GNU ls does when following links (ls -H or -L). The target is
+	// found with the DirEntry's Stat method, if it has one (fastwalk's does),
+	// or else os.Stat.
+	FollowLinks bool
+
+	// TypeRules color files by both their type and extension. The first
+	// matching rule is used and takes precedence over all of the other
+	// rules used by MatchEntry. Disabling RuleExts disables them as well.
+	TypeRules []TypeRule
+
+	// SizeRules color regular files by their size. The rule with the
+	// largest Min that a file satisfies is used and takes precedence over
+	// all other rules, including extensions. Since it requires the file's
+	// size it is only used by MatchInfo and the methods that stat files.
+	SizeRules []SizeRule
+
+	// AgeRules color regular files by their modification time. The first
+	// matching rule is used. They are consulted after SizeRules and, like
+	// them, take precedence over all other rules and are only used by
+	// MatchInfo.
+	AgeRules []AgeRule
+
+	// DeviceRules color block and character devices by their major device
+	// number, for example to color TTYs and disks differently. The first
+	// matching rule is used and takes precedence over BD and CD. Since it
+	// requires the file's device number it is only used by MatchInfo and
+	// is only supported on Unix.
+	DeviceRules []DeviceRule
+
+	// OwnedColor and OtherColor, if set, color regular files owned by the
+	// current user and by other users respectively. They are consulted
+	// after AgeRules and, like them, are only used by MatchInfo. File
+	// ownership is only supported on Unix.
+	OwnedColor ColorExtension
+	OtherColor ColorExtension
+
+	// WhiteoutColor, if set, colors the whiteout entries that overlay and
+	// other union filesystems use to mark files deleted from a lower
+	// layer. On Linux these are character devices with device number 0:0,
+	// which is the heuristic used to detect them: it requires the file's
+	// FileInfo so MatchEntry calls the DirEntry's Info method for
+	// character devices. Whiteouts are not detected on other platforms.
+	WhiteoutColor ColorExtension
+
+	// ShebangRules, if set, map interpreter names, such as "bash" or
+	// "python3", to the color used by MatchShebang for executable scripts
+	// run by that interpreter.
+	ShebangRules map[string]ColorExtension
+
+	// Now returns the current time used by AgeRules. If nil, time.Now is
+	// used. It is mainly useful for testing.
+	Now func() time.Time
+
+	// SeparatorColor, if set, is the color of the path separators written
+	// by AppendPath and FormatComponents. An empty Seq leaves them
+	// uncolored. If nil, AppendPath colors the separators of the directory
+	// with DI and FormatComponents leaves them uncolored.
+	SeparatorColor *ColorExtension
+
+	// Git, if set, are the colors used by MatchWithGitStatus.
+	Git *GitColors
+
+	// Normalize, if set, is applied to file names before they are matched
+	// against the extension, glob and type rules. It can be used to apply
+	// Unicode normalization, such as golang.org/x/text/unicode/norm's
+	// NFC.String, so that names in a different normal form than the rules
+	// still match. Rules are not normalized.
+	Normalize func(string) string
+
+	// ExtraMatch, if set, is called before any of the other rules and the
+	// color it returns, if not nil, is used. It lets callers classify files
+	// by properties this package does not know about, such as extended
+	// attributes or project conventions. MatchInfo passes it the FileInfo
+	// converted with fs.FileInfoToDirEntry. It is not used by MatchName,
+	// which has no DirEntry. The key reported by MatchEntryKey is "extra".
+	ExtraMatch func(path string, d fs.DirEntry) *ColorExtension
+
+	// Groups maps lower case extensions to the groups returned by Group. If
+	// nil, DefaultGroups is used.
+	Groups map[string]string
+
+	// Disabled are the rules that are ignored when matching. Unlike Disable
+	// the sequences are left intact so the rules can be re-enabled later.
+	// A disabled indicator is treated as if it were unset.
+	Disabled Rules
+
+	// HiddenColor, if set, is used for all files and directories whose name
+	// starts with a '.' (other than "." and "..") unless an extension rule
+	// matches.
+	HiddenColor ColorExtension
+
+	// DotColor, if set, is used for the special "." and ".." directory
+	// entries instead of DI.
+	DotColor ColorExtension
+
+	// DepthColors, if set, color directories by their depth in a walk,
+	// for example to make top-level directories stand out. DepthColors[i]
+	// is used for directories at depth i, where the root of the walk is at
+	// depth 0, and empty or missing colors fall back to the normal rules.
+	// It is used by MatchDepth and FormatTree.
+	DepthColors []ColorExtension
+
+	// invalid are the segments that could not be parsed.
+	invalid []string
+
+	// extOrder are the extensions of Exts in the order they were declared.
+	// It may contain duplicates and extensions that have been removed.
+	extOrder []string
+
+	// shadowed are the rules that were replaced by a later declaration of
+	// the same pattern when c was parsed or finalized (see ShadowedRules).
+	shadowed []ColorExtension
+
+	// finalized is set once Exts is known to be sorted (see Finalize).
+	finalized bool
+
+	// ColorMissing makes ColorPath color paths that do not exist with MI
+	// (or OR if MI is not set) instead of returning an error.
+	ColorMissing bool
 }
 
-func (c LSColors) String() string {
-	n := 40 // 40 for all the base colors which need 4 chars each ("di=:")
-	for _, e := range []*ColorExtension{
+// Rules is a set of rule kinds that may be disabled with LSColors.Disabled.
+type Rules uint32
+
+// The rule kinds. The indicator kinds are in the same order as the
+// indicators in LSColors.
+const (
+	RuleDI Rules = 1 << iota
+	RuleFI
+	RuleLN
+	RulePI
+	RuleSO
+	RuleBD
+	RuleCD
+	RuleOR
+	RuleMI
+	RuleEX
+	RuleTW
+	RuleNO
+	RuleST
+	RuleOW
+	RuleRS
+	RuleExts   // Exts and Globs
+	RuleHidden // HiddenColor
+)
+
+// set returns if e is set and rule r is not disabled.
+func (c *LSColors) set(e *ColorExtension, r Rules) bool {
+	return c.Disabled&r == 0 && !e.Empty()
+}
+
+// indicators returns pointers to all of the named indicators in the order
+// they are serialized by String.
+func (c *LSColors) indicators() [15]*ColorExtension {
+	return [...]*ColorExtension{
 		&c.DI, &c.FI, &c.LN, &c.PI, &c.SO,
 		&c.BD, &c.CD, &c.OR, &c.MI, &c.EX,
-	} {
-		n += len(e.Seq)
+		&c.TW, &c.NO, &c.ST, &c.OW, &c.RS,
+	}
+}
+
+// isCodeKey reports if key names an escape code of LS_COLORS rather than a
+// color (see code).
+func isCodeKey(key string) bool {
+	return key == "lc" || key == "rc" || key == "ec"
+}
+
+// code returns the escape code named by the LS_COLORS key, which is "lc",
+// "rc" or "ec", or nil if key does not name one.
+func (c *LSColors) code(key string) *string {
+	switch key {
+	case "lc":
+		return &c.LeftCode
+	case "rc":
+		return &c.RightCode
+	case "ec":
+		return &c.EndCode
+	}
+	return nil
+}
+
+// indicatorKeys are the LS_COLORS keys of the indicators returned by
+// indicators, in the same order.
+var indicatorKeys = [...]string{
+	"di", "fi", "ln", "pi", "so",
+	"bd", "cd", "or", "mi", "ex",
+	"tw", "no", "st", "ow", "rs",
+}
+
+// Range calls fn with the key and sequence of every rule that is set, in
+// the order used by String: the named indicators, then the extensions
+// (keyed by their pattern, such as "*.go") and then the globs. Range stops
+// if fn returns false.
+func (c *LSColors) Range(fn func(key, seq string) bool) {
+	for i, e := range c.indicators() {
+		if e.Seq != "" && !fn(indicatorKeys[i], e.Seq) {
+			return
+		}
 	}
-	// We strip the '*' from the ext so need to account for that
-	n += len(c.Exts) * 3
 	for _, e := range c.Exts {
-		n += len(e.Ext) + len(e.Seq)
+		if !fn("*"+e.Ext, e.Seq) {
+			return
+		}
 	}
-	var w strings.Builder
-	w.Grow(n)
-	for _, e := range []*ColorExtension{
-		&c.DI, &c.FI, &c.LN, &c.PI, &c.SO,
-		&c.BD, &c.CD, &c.OR, &c.MI, &c.EX,
-	} {
+	for _, e := range c.Globs {
+		if !fn(e.Ext, e.Seq) {
+			return
+		}
+	}
+}
+
+// Entries returns every rule of c that is set as a flat slice, in the
+// order used by Range, with the Ext of each set to the rule's key: the
+// named indicators ("di"), then the extensions ("*.go") and then the globs
+// (".git*"). It allows the whole palette to be inspected uniformly.
+func (c *LSColors) Entries() []ColorExtension {
+	entries := make([]ColorExtension, 0, len(indicatorKeys)+len(c.Exts)+len(c.Globs))
+	c.Range(func(key, seq string) bool {
+		entries = append(entries, ColorExtension{Ext: key, Seq: seq})
+		return true
+	})
+	return entries
+}
+
+// SerializedLen returns the exact length in bytes of the string returned by
+// String. It can be used to size buffers before serializing the palette.
+func (c *LSColors) SerializedLen() int {
+	n := 0
+	for _, e := range c.indicators() {
+		if len(e.Ext) != 0 && len(e.Seq) != 0 {
+			n += len(e.Ext) + len("=:") + len(e.Seq)
+		}
+	}
+	for _, e := range c.Exts {
+		if len(e.Ext) != 0 && len(e.Seq) != 0 {
+			// We strip the '*' from the ext so need to account for that
+			n += len("*") + len(e.Ext) + len("=:") + len(e.Seq)
+		}
+	}
+	for _, e := range c.Globs {
+		if len(e.Ext) != 0 && len(e.Seq) != 0 {
+			n += len(e.Ext) + len("=:") + len(e.Seq)
+		}
+	}
+	if n > 0 {
+		n-- // no trailing ':'
+	}
+	return n
+}
+
+func (c *LSColors) writeString(w *strings.Builder) {
+	for _, e := range c.indicators() {
 		if len(e.Ext) != 0 && len(e.Seq) != 0 {
 			if w.Len() > 0 {
 				w.WriteByte(':')
@@ -198,237 +667,1387 @@ func (c LSColors) String() string {
 		w.WriteByte('=')
 		w.WriteString(e.Seq)
 	}
+	for _, e := range c.Globs {
+		if len(e.Ext) == 0 || len(e.Seq) == 0 {
+			continue // this should not happen
+		}
+		if w.Len() > 0 {
+			w.WriteByte(':')
+		}
+		w.WriteString(e.Ext)
+		w.WriteByte('=')
+		w.WriteString(e.Seq)
+	}
+}
+
+func (c LSColors) String() string {
+	var w strings.Builder
+	w.Grow(c.SerializedLen())
+	c.writeString(&w)
 	return w.String()
 }
 
-func isBrokenLink(path string, d fs.DirEntry) bool {
-	// Check for a fastwalk.DirEntry
-	if de, ok := d.(interface{ Stat() (fs.FileInfo, error) }); ok {
-		_, err := de.Stat()
-		return err != nil
+// indicator returns a pointer to the named indicator for key, which must be
+// one of the two letter LS_COLORS keys ("di", "ln", etc.), or nil if key is
+// not an indicator.
+func (c *LSColors) indicator(key string) *ColorExtension {
+	switch key {
+	case "di":
+		return &c.DI
+	case "fi":
+		return &c.FI
+	case "ln":
+		return &c.LN
+	case "pi":
+		return &c.PI
+	case "so":
+		return &c.SO
+	case "bd":
+		return &c.BD
+	case "cd":
+		return &c.CD
+	case "or":
+		return &c.OR
+	case "mi":
+		return &c.MI
+	case "ex":
+		return &c.EX
+	case "tw":
+		return &c.TW
+	case "no":
+		return &c.NO
+	case "st":
+		return &c.ST
+	case "ow":
+		return &c.OW
+	case "rs":
+		return &c.RS
 	}
-	_, err := os.Stat(path)
-	return err != nil
+	return nil
 }
 
-func (c *LSColors) MatchEntry(path string, d fs.DirEntry) *ColorExtension {
-	var ext *ColorExtension
-	typ := d.Type()
-	switch {
-	case typ.IsDir() && !c.DI.Empty():
-		ext = &c.DI
-	case typ.IsRegular():
-		if typ&0111 != 0 && !c.EX.Empty() {
-			ext = &c.EX
-		} else if !c.FI.Empty() {
-			ext = &c.FI
+// Disable clears the indicators and extension rules named by keys so that
+// they no longer match. Indicators are named by their LS_COLORS key ("di",
+// "ln", etc.) and extensions by their pattern ("*.tar"). Unknown keys are
+// ignored.
+func (c *LSColors) Disable(keys ...string) {
+	for _, key := range keys {
+		if e := c.indicator(key); e != nil {
+			*e = ColorExtension{}
+			continue
 		}
-	case typ&fs.ModeSymlink != 0:
-		// TODO: make sure this matches the `ls` broken link logic
-		if !c.LN.Empty() {
-			ext = &c.LN
+		if isGlob(key) {
+			c.Globs = slices.DeleteFunc(c.Globs, func(e ColorExtension) bool {
+				return e.Ext == key
+			})
+		} else if ext, ok := strings.CutPrefix(key, "*"); ok {
+			c.Exts = slices.DeleteFunc(c.Exts, func(e ColorExtension) bool {
+				return e.Ext == ext
+			})
+			delete(c.Names, ext)
 		}
-		if !c.OR.Empty() && isBrokenLink(path, d) {
-			ext = &c.OR
+	}
+}
+
+// PatchString returns an LS_COLORS string containing only the indicators
+// and extensions of c that are not set to the same value in base. Appending
+// the result to base.String() (separated by a ':') and parsing it yields c.
+// The result is empty if c and base are equal.
+//
+// Rules that are set in base but not in c cannot be expressed in LS_COLORS
+// and are omitted.
+func (c *LSColors) PatchString(base *LSColors) string {
+	var w strings.Builder
+	add := func(prefix string, e *ColorExtension) {
+		if w.Len() > 0 {
+			w.WriteByte(':')
 		}
-	case typ&fs.ModeNamedPipe != 0 && !c.PI.Empty():
-		ext = &c.PI
-	case typ&fs.ModeSocket != 0 && !c.PI.Empty():
-		ext = &c.PI
-	case typ&fs.ModeDevice != 0 && !c.BD.Empty():
-		ext = &c.BD
-	case typ&fs.ModeCharDevice != 0 && !c.CD.Empty():
-		ext = &c.CD
-	case typ&0111 != 0 && !c.EX.Empty():
-		ext = &c.EX
-	default:
-		// TODO: GNU ls marks other files as broken links C_ORPHAN
-		if !c.OR.Empty() {
-			ext = &c.OR
+		w.WriteString(prefix)
+		w.WriteString(e.Ext)
+		w.WriteByte('=')
+		w.WriteString(e.Seq)
+	}
+	bi := base.indicators()
+	for i, e := range c.indicators() {
+		if !e.Empty() && (e.Ext != bi[i].Ext || e.Seq != bi[i].Seq) {
+			add("", e)
 		}
 	}
-	if typ.IsRegular() && ext != &c.EX {
-		if e := c.matchExt(d.Name()); e != nil {
-			return e
+	exts := make(map[string]string, len(base.Exts))
+	for _, e := range base.Exts {
+		exts[e.Ext] = e.Seq
+	}
+	for i := range c.Exts {
+		if seq, ok := exts[c.Exts[i].Ext]; !ok || seq != c.Exts[i].Seq {
+			add("*", &c.Exts[i])
 		}
 	}
-	if ext == nil {
-		return &NoColor
+	for i := range c.Globs {
+		e := &c.Globs[i]
+		if !slices.ContainsFunc(base.Globs, func(g ColorExtension) bool {
+			return g.Ext == e.Ext && g.Seq == e.Seq
+		}) {
+			add("", e)
+		}
 	}
-	return ext
+	return w.String()
 }
 
-func (c *LSColors) MatchInfo(path string, d fs.FileInfo) *ColorExtension {
-	var ext *ColorExtension
-	typ := d.Mode()
-	switch {
-	case typ.IsDir() && !c.DI.Empty():
-		ext = &c.DI
-	case typ.IsRegular():
-		if typ&0111 != 0 && !c.EX.Empty() {
-			ext = &c.EX
-		} else if !c.FI.Empty() {
-			ext = &c.FI
-		}
-	case typ&fs.ModeSymlink != 0:
-		// TODO: make sure this matches the `ls` broken link logic
-		if !c.LN.Empty() {
-			ext = &c.LN
-		}
-		if !c.OR.Empty() && isBrokenLink(path, fs.FileInfoToDirEntry(d)) {
-			ext = &c.OR
+// Set sets the color sequence of the rule named by key, which may be an
+// indicator ("di"), an extension ("*.go") or a glob (".git*"). An error is
+// returned if seq is not a valid SGR parameter string, which prevents other
+// escape sequences from being injected into the output, or if key is not
+// valid.
+func (c *LSColors) Set(key, seq string) error {
+	if !validSequence(seq) {
+		return fmt.Errorf("lscolors: invalid color sequence for %q: %q", key, seq)
+	}
+	if e := c.indicator(key); e != nil {
+		*e = ColorExtension{Ext: key, Seq: seq}
+		return nil
+	}
+	if isGlob(key) {
+		if _, err := path.Match(key, ""); err != nil {
+			return fmt.Errorf("lscolors: invalid pattern %q: %w", key, err)
 		}
-	case typ&fs.ModeNamedPipe != 0 && !c.PI.Empty():
-		ext = &c.PI
-	case typ&fs.ModeSocket != 0 && !c.PI.Empty():
-		ext = &c.PI
-	case typ&fs.ModeDevice != 0 && !c.BD.Empty():
-		ext = &c.BD
-	case typ&fs.ModeCharDevice != 0 && !c.CD.Empty():
-		ext = &c.CD
-	default:
-		// TODO: GNU ls marks other files as broken links C_ORPHAN
-		if !c.OR.Empty() {
-			ext = &c.OR
+		if i := slices.IndexFunc(c.Globs, func(e ColorExtension) bool {
+			return e.Ext == key
+		}); i >= 0 {
+			c.Globs[i].Seq = seq
+		} else {
+			c.Globs = append(c.Globs, ColorExtension{Ext: key, Seq: seq})
 		}
+		return nil
 	}
-	if typ.IsRegular() && ext != &c.EX {
-		if e := c.matchExt(d.Name()); e != nil {
-			return e
-		}
+	ext, ok := strings.CutPrefix(key, "*")
+	if !ok || ext == "" {
+		return fmt.Errorf("lscolors: invalid key: %q", key)
 	}
-	if ext == nil {
-		return &NoColor
+	if !c.finalized {
+		c.Finalize()
 	}
-	return ext
+	e := ColorExtension{Ext: ext, Seq: seq}
+	i, found := slices.BinarySearchFunc(c.Exts, e, compareExt)
+	if found {
+		c.Exts[i] = e
+	} else {
+		c.Exts = slices.Insert(c.Exts, i, e)
+		c.extOrder = append(c.extOrder, ext)
+	}
+	c.setName(ext, seq)
+	return nil
 }
 
-func (c *LSColors) matchExt(name string) *ColorExtension {
-	// TODO: could sort in reverse then use a binary search on length
-	// that way the first match is the longest
+// rule returns the rule named by key, which is named as for Set, or nil if
+// there is no such rule.
+func (c *LSColors) rule(key string) *ColorExtension {
+	if e := c.indicator(key); e != nil {
+		return e
+	}
+	if isGlob(key) {
+		if i := slices.IndexFunc(c.Globs, func(e ColorExtension) bool {
+			return e.Ext == key
+		}); i >= 0 {
+			return &c.Globs[i]
+		}
+		return nil
+	}
+	ext, ok := strings.CutPrefix(key, "*")
+	if !ok || ext == "" {
+		return nil
+	}
+	if !c.finalized {
+		c.Finalize()
+	}
+	if i, found := slices.BinarySearchFunc(c.Exts, ColorExtension{Ext: ext}, compareExt); found {
+		return &c.Exts[i]
+	}
+	return nil
+}
 
-	// Find longest pattern
-	var sfx *ColorExtension
-	for i := range c.Exts {
-		e := &c.Exts[i]
-		if len(e.Ext) > len(name) {
-			break
+// SetAttribute adds, if on is true, or removes the SGR attribute attr, such
+// as 1 (bold) or 4 (underline), from the sequence of the rule named by key,
+// which is named as for Set. An attribute is added to the start of the
+// sequence ("01;32"), after any leading reset ("00;01;36"), and removing
+// the only parameter of a sequence leaves "0". The resulting sequence is
+// validated like it is by Set. An error is returned if attr is not an
+// attribute (1-9) or if the rule is not set.
+func (c *LSColors) SetAttribute(key string, attr int, on bool) error {
+	if attr < 1 || attr > 9 {
+		return fmt.Errorf("lscolors: invalid SGR attribute: %d", attr)
+	}
+	e := c.rule(key)
+	if e == nil || e.Seq == "" {
+		return fmt.Errorf("lscolors: no rule for %q", key)
+	}
+	found := false
+	seq := mapSGR(e.Seq, func(p int, s string) string {
+		if p != attr {
+			return s
 		}
-		if e.MatchExt(name) {
-			sfx = e
+		found = true
+		if on {
+			return s
 		}
+		return ""
+	})
+	switch {
+	case on && !found:
+		// Insert attr after any leading reset, which would clear it.
+		n := 0
+		for n < len(seq) {
+			p, _, _ := strings.Cut(seq[n:], ";")
+			if strings.Trim(p, "0") != "" {
+				break
+			}
+			n += len(p) + 1
+		}
+		if n > len(seq) {
+			seq += ";0" + strconv.Itoa(attr)
+		} else {
+			seq = seq[:n] + "0" + strconv.Itoa(attr) + ";" + seq[n:]
+		}
+	case seq == "":
+		seq = "0"
 	}
-	return sfx
+	return c.Set(key, seq)
 }
 
-func isDigit(c byte) bool { return '0' <= c && c <= '9' }
+// ShadowedRules returns the extension and glob rules of c that can never
+// match, with their Ext set to the key used by Set ("*.go" or ".git*"):
+// rules that were replaced by a later declaration of the same pattern,
+// globs that are identical to an earlier glob, extensions that contain a
+// '/', which file names never do, and if MaxSuffixDepth is set extensions
+// with more than MaxSuffixDepth dots. The result can be used to clean up a
+// configuration.
+//
+// Extensions are never shadowed by longer extensions: "*.gz" still matches
+// "a.gz" if "*.tar.gz" is set.
+func (c *LSColors) ShadowedRules() []ColorExtension {
+	if !c.finalized {
+		c.Finalize()
+	}
+	rules := slices.Clone(c.shadowed)
+	for i, e := range c.Globs {
+		if slices.ContainsFunc(c.Globs[:i], func(g ColorExtension) bool {
+			return g.Ext == e.Ext
+		}) {
+			rules = append(rules, e)
+		}
+	}
+	for _, e := range c.Exts {
+		if strings.Contains(e.Ext, "/") ||
+			c.MaxSuffixDepth > 0 && strings.Count(e.Ext, ".") > c.MaxSuffixDepth {
+			rules = append(rules, ColorExtension{Ext: "*" + e.Ext, Seq: e.Seq})
+		}
+	}
+	return rules
+}
 
-func validSequence(s string) bool {
-	if len(s) == 0 || !isDigit(s[0]) {
-		return false
+// AddExtensions adds the extension rules exts to c, replacing existing
+// rules for the same extensions. If exts contains duplicates the last is
+// used. It is more efficient than calling Set for each rule since Exts is
+// only sorted once. A leading '*' is removed from each Ext and rules with an
+// empty Ext are ignored. Unlike Set the sequences are not validated, use
+// Validate to check them.
+func (c *LSColors) AddExtensions(exts []ColorExtension) {
+	if !c.finalized {
+		c.Finalize()
 	}
-	n := 1
-	for i := 1; i < len(s); i++ {
-		c := s[i]
-		switch {
-		case isDigit(c):
-			n++
-			if n > 3 {
-				return false
-			}
-		case c == ';':
-			n = 0
-		default:
-			return false
+	n := len(c.shadowed)
+	c.Exts = slices.Grow(c.Exts, len(exts))
+	for _, e := range exts {
+		e.Ext = strings.TrimPrefix(e.Ext, "*")
+		if e.Ext != "" {
+			c.Exts = append(c.Exts, e)
+			c.extOrder = append(c.extOrder, e.Ext)
+			c.setName(e.Ext, e.Seq)
 		}
 	}
-	return isDigit(s[len(s)-1])
+	c.Finalize()
+	c.shadowed = c.shadowed[:n] // replaced rules are not shadowed
 }
 
-func ParseLSColors(clrs string) (*LSColors, error) {
-	if clrs == "" {
-		return nil, errors.New("ls_colors: empty LS_COLORS argument")
+// ExtensionsInOrder returns the extension rules of c in the order they were
+// first declared, instead of the longest first order used for matching.
+// This allows callers to implement other matching policies, such as first
+// declared wins. Rules whose declaration order is unknown, such as ones
+// added to Exts directly, follow in the order of Exts.
+func (c *LSColors) ExtensionsInOrder() []ColorExtension {
+	if !c.finalized {
+		c.Finalize()
 	}
-	var invalid []string
-	var ls LSColors
-	for len(clrs) > 0 {
-		var s string
-		if i := strings.IndexByte(clrs, ':'); i >= 0 {
-			s = clrs[:i]
-			clrs = clrs[i+1:]
-		} else {
-			s = clrs // EOF
-			clrs = ""
+	exts := make([]ColorExtension, 0, len(c.Exts))
+	seen := make([]bool, len(c.Exts))
+	for _, ext := range c.extOrder {
+		i, ok := slices.BinarySearchFunc(c.Exts, ColorExtension{Ext: ext}, compareExt)
+		if ok && !seen[i] {
+			seen[i] = true
+			exts = append(exts, c.Exts[i])
 		}
-		k, v, ok := strings.Cut(s, "=")
-		if !ok || k == "" || v == "" {
-			invalid = append(invalid, s)
-			continue
+	}
+	for i, e := range c.Exts {
+		if !seen[i] {
+			exts = append(exts, e)
 		}
-		switch k {
-		case "di":
-			ls.DI = ColorExtension{Ext: "di", Seq: v}
-		case "fi":
-			ls.FI = ColorExtension{Ext: "fi", Seq: v}
-		case "ln":
-			ls.LN = ColorExtension{Ext: "ln", Seq: v}
-		case "pi":
-			ls.PI = ColorExtension{Ext: "pi", Seq: v}
-		case "so":
-			ls.SO = ColorExtension{Ext: "so", Seq: v}
-		case "bd":
-			ls.BD = ColorExtension{Ext: "bd", Seq: v}
-		case "cd":
-			ls.CD = ColorExtension{Ext: "cd", Seq: v}
-		case "or":
-			ls.OR = ColorExtension{Ext: "or", Seq: v}
-		case "mi":
-			ls.MI = ColorExtension{Ext: "mi", Seq: v}
-		case "ex":
-			ls.EX = ColorExtension{Ext: "ex", Seq: v}
-		case "tw":
-			ls.TW = ColorExtension{Ext: "tw", Seq: v}
-		case "no":
-			ls.NO = ColorExtension{Ext: "no", Seq: v}
-		case "st":
-			ls.ST = ColorExtension{Ext: "st", Seq: v}
-		case "ow":
-			ls.OW = ColorExtension{Ext: "ow", Seq: v}
-		default:
-			if ls.Exts == nil {
-				// Lazily allocate
-				ls.Exts = make([]ColorExtension, 0, strings.Count(clrs, ":")+1)
+	}
+	return exts
+}
+
+// CompareExtensions compares extension rules by the length and then the
+// name of their Ext, which is the order Finalize sorts Exts in. It can be
+// passed to slices.SortFunc to sort rules built by hand.
+func CompareExtensions(a, b ColorExtension) int { return compareExt(a, b) }
+
+// ExtensionLess reports if a sorts before b in the order used by
+// CompareExtensions.
+func ExtensionLess(a, b ColorExtension) bool { return compareExt(a, b) < 0 }
+
+// compareExt orders extensions by length and then name, which is the order
+// matchExt requires.
+func compareExt(a, b ColorExtension) int {
+	if len(a.Ext) != len(b.Ext) {
+		return len(a.Ext) - len(b.Ext)
+	}
+	return strings.Compare(a.Ext, b.Ext)
+}
+
+// Validate returns an error describing every rule of c whose sequence is
+// not a valid SGR parameter string, including the colors that are not
+// part of LS_COLORS such as HiddenColor, TypeRules and Git, every
+// extension or glob with an empty pattern or sequence and, if c has been
+// finalized, whether Exts is no longer sorted in the order required for
+// matching. Sequences added by
+// ParseLSColors and Set are always valid, but those assigned directly may
+// contain arbitrary escape sequences (such as ones that move the cursor)
+// that should not be written to a terminal.
+func (c *LSColors) Validate() error {
+	var errs []error
+	c.eachColor(func(key string, e *ColorExtension) {
+		if e.Seq != "" && !validSequence(e.Seq) {
+			errs = append(errs, fmt.Errorf("lscolors: invalid color sequence for %q: %q", key, e.Seq))
+		}
+	})
+	for _, e := range c.Exts {
+		switch {
+		case e.Ext == "":
+			errs = append(errs, fmt.Errorf("lscolors: empty extension with sequence: %q", e.Seq))
+		case e.Seq == "":
+			errs = append(errs, fmt.Errorf("lscolors: empty color sequence for %q", "*"+e.Ext))
+		}
+	}
+	for _, e := range c.Globs {
+		switch {
+		case e.Ext == "":
+			errs = append(errs, fmt.Errorf("lscolors: empty glob with sequence: %q", e.Seq))
+		case e.Seq == "":
+			errs = append(errs, fmt.Errorf("lscolors: empty color sequence for %q", e.Ext))
+		}
+	}
+	// Exts is sorted by Finalize, which is called lazily, so it only has to
+	// be sorted once c is finalized.
+	if c.finalized && !slices.IsSortedFunc(c.Exts, compareExt) {
+		errs = append(errs, errors.New("lscolors: Exts modified without calling Finalize: not sorted"))
+	}
+	return errors.Join(errs...)
+}
+
+// statLink returns the FileInfo of the target of the symbolic link at path.
+func statLink(path string, d fs.DirEntry) (fs.FileInfo, error) {
+	// Check for a fastwalk.DirEntry
+	if de, ok := d.(interface{ Stat() (fs.FileInfo, error) }); ok {
+		return de.Stat()
+	}
+	return os.Stat(path)
+}
+
+// MatchEntry returns the color for the file at path with DirEntry d.
+//
+// Like GNU ls, a broken symbolic link is colored with OR, or LN if OR is not
+// set. MI is never used for the link itself: GNU ls only uses it to color
+// the missing target in long listings (ls -l).
+//
+// The returned ColorExtension is not a copy: it points to a field of c, an
+// element of c.Exts or c.Globs, or NoColor. It must not be modified and is
+// only valid until c is next modified. Use MatchEntryCopy to retain it.
+func (c *LSColors) MatchEntry(path string, d fs.DirEntry) *ColorExtension {
+	ext, _ := c.match(path, d.Name(), d.Type(), d)
+	return ext
+}
+
+// NormalColor returns the baseline color of c: NO if it is set, otherwise
+// FI if it is set and otherwise NoColor. Files that no rule matches are
+// colored with NO, or NoColor if it is not set.
+func (c *LSColors) NormalColor() *ColorExtension {
+	switch {
+	case c.set(&c.NO, RuleNO):
+		return &c.NO
+	case c.set(&c.FI, RuleFI):
+		return &c.FI
+	}
+	return &NoColor
+}
+
+// MatchName returns the color for a file with the given name and mode,
+// which is useful when only the name and type of a file are known. Broken
+// symbolic links are detected by calling os.Stat on name.
+func (c *LSColors) MatchName(name string, mode fs.FileMode) *ColorExtension {
+	ext, _ := c.match(name, filepath.Base(name), mode, nil)
+	return ext
+}
+
+// MatchEntries returns the colors of entries, which are the entries of the
+// directory dir (as returned by os.ReadDir). It is equivalent to calling
+// MatchEntry for each entry with the path filepath.Join(dir, name) but is
+// faster since the path of an entry is only constructed when it is
+// required: for symbolic links, which need to be stat'd to detect if they
+// are broken, and when ExtraMatch or ScriptsBeatExecutable is set.
+func (c *LSColors) MatchEntries(dir string, entries []fs.DirEntry) []*ColorExtension {
+	exts := make([]*ColorExtension, len(entries))
+	for i, d := range entries {
+		name := d.Name()
+		typ := d.Type()
+		var path string
+		if typ&fs.ModeSymlink != 0 || c.ExtraMatch != nil || c.ScriptsBeatExecutable {
+			path = filepath.Join(dir, name)
+		}
+		exts[i], _ = c.match(path, name, typ, d)
+	}
+	return exts
+}
+
+// MatchEntryCopy is like MatchEntry but returns a copy of the matching
+// ColorExtension, which remains valid after c is modified.
+func (c *LSColors) MatchEntryCopy(path string, d fs.DirEntry) ColorExtension {
+	ext, _ := c.match(path, d.Name(), d.Type(), d)
+	return *ext
+}
+
+// MatchEntryKey is like MatchEntry but also returns the key of the rule that
+// matched, such as "di", "ex" or "*.tar.gz". The key is empty if nothing
+// matched. This is mainly useful for debugging why a file has a given color.
+func (c *LSColors) MatchEntryKey(path string, d fs.DirEntry) (*ColorExtension, string) {
+	ext, key := c.match(path, d.Name(), d.Type(), d)
+	if key == extKey {
+		key = "*" + ext.Ext
+	}
+	return ext, key
+}
+
+// MatchInfo returns the color for the file at path with FileInfo d.
+func (c *LSColors) MatchInfo(path string, d fs.FileInfo) *ColorExtension {
+	return c.matchInfo(path, d, nil)
+}
+
+// matchInfo implements MatchInfo. The DirEntry d is optional and is used
+// to stat symbolic links if it has a Stat method (see statLink).
+func (c *LSColors) matchInfo(path string, fi fs.FileInfo, d fs.DirEntry) *ColorExtension {
+	if c.ExtraMatch != nil {
+		de := d
+		if de == nil {
+			de = fs.FileInfoToDirEntry(fi)
+		}
+		if e := c.ExtraMatch(path, de); e != nil {
+			return e
+		}
+	}
+	if e := c.matchInfoRules(fi); e != nil {
+		return e
+	}
+	ext, _ := c.matchRules(path, fi.Name(), fi.Mode(), d)
+	return ext
+}
+
+// Clone returns a deep copy of c.
+func (c *LSColors) Clone() *LSColors {
+	ls := *c
+	ls.Exts = slices.Clone(c.Exts)
+	ls.Globs = slices.Clone(c.Globs)
+	ls.TypeRules = slices.Clone(c.TypeRules)
+	ls.DepthColors = slices.Clone(c.DepthColors)
+	ls.SizeRules = slices.Clone(c.SizeRules)
+	ls.AgeRules = slices.Clone(c.AgeRules)
+	ls.DeviceRules = slices.Clone(c.DeviceRules)
+	ls.invalid = slices.Clone(c.invalid)
+	ls.extOrder = slices.Clone(c.extOrder)
+	ls.shadowed = slices.Clone(c.shadowed)
+	if c.Names != nil {
+		ls.Names = make(map[string]*ColorExtension, len(c.Names))
+		for name, e := range c.Names {
+			if e != nil {
+				e := *e
+				ls.Names[name] = &e
 			}
-			if strings.HasPrefix(k, "*") {
-				if !validSequence(v) {
-					invalid = append(invalid, s)
-					continue
+		}
+	}
+	ls.ShebangRules = maps.Clone(c.ShebangRules)
+	ls.Groups = maps.Clone(c.Groups)
+	if c.Git != nil {
+		g := *c.Git
+		ls.Git = &g
+	}
+	if c.SeparatorColor != nil {
+		sep := *c.SeparatorColor
+		ls.SeparatorColor = &sep
+	}
+	return &ls
+}
+
+// mapSeqs returns a copy of c with fn applied to the sequence of every rule,
+// including the ones that are not part of LS_COLORS.
+func (c *LSColors) mapSeqs(fn func(seq string) string) *LSColors {
+	ls := c.Clone()
+	ls.eachColor(func(_ string, e *ColorExtension) {
+		if e.Seq != "" {
+			e.Seq = fn(e.Seq)
+		}
+	})
+	return ls
+}
+
+// StripAttribute returns a copy of c with the SGR attribute attr, such as
+// 1 (bold), 2 (dim) or 5 (blink), removed from every sequence. Sequences
+// that only consisted of attr are replaced with "0". Parameters of extended
+// (256 and truecolor) colors are never removed.
+func (c *LSColors) StripAttribute(attr int) *LSColors {
+	return c.mapSeqs(func(seq string) string {
+		seq = mapSGR(seq, func(p int, s string) string {
+			if p == attr {
+				return ""
+			}
+			return s
+		})
+		if seq == "" {
+			seq = "0"
+		}
+		return seq
+	})
+}
+
+// BoldToBright returns a copy of c where sequences that combine bold with
+// one of the 8 basic colors, such as "01;34", use the bright version of the
+// color ("94") instead, since many configurations use bold to mean bright
+// but some terminals render it as a heavier font. Basic backgrounds are
+// converted as well ("01;41" becomes "101"). Sequences without both bold and
+// a basic color are not modified.
+func (c *LSColors) BoldToBright() *LSColors {
+	return c.mapSeqs(func(seq string) string {
+		var bold, basic bool
+		mapSGR(seq, func(p int, s string) string {
+			bold = bold || p == 1
+			basic = basic || 30 <= p && p <= 37 || 40 <= p && p <= 47
+			return s
+		})
+		if !bold || !basic {
+			return seq
+		}
+		return mapSGR(seq, func(p int, s string) string {
+			switch {
+			case p == 1:
+				return ""
+			case 30 <= p && p <= 37, 40 <= p && p <= 47:
+				return strconv.Itoa(p + 60)
+			}
+			return s
+		})
+	})
+}
+
+// Reset returns the reset sequence configured by the palette, which is
+// built like GNU ls does: EndCode if it is set and otherwise the "rs"
+// sequence, or "0", between LeftCode and RightCode, which default to
+// "\x1b[" and "m". Note that the Format methods always end with a full
+// "\x1b[0m" reset since a customized reset may not clear every attribute.
+func (c *LSColors) Reset() string {
+	if c.EndCode != "" {
+		return c.EndCode
+	}
+	left, rs, right := "\x1b[", "0", "m"
+	if c.LeftCode != "" {
+		left = c.LeftCode
+	}
+	if c.RS.Seq != "" {
+		rs = c.RS.Seq
+	}
+	if c.RightCode != "" {
+		right = c.RightCode
+	}
+	if left == "\x1b[" && rs == "0" && right == "m" {
+		return resetSeq
+	}
+	return left + rs + right
+}
+
+// Invalid returns the raw segments (such as "di=xyz") that could not be
+// parsed when c was created. It is empty if there were no parse errors.
+// The returned slice must not be modified.
+func (c *LSColors) Invalid() []string {
+	return c.invalid
+}
+
+// ColorPath returns the base name of path colored by the type of the file
+// it names. The file is not followed if it is a symbolic link. If path does
+// not exist an error is returned unless ColorMissing is set.
+func (c *LSColors) ColorPath(path string) (string, error) {
+	name := filepath.Base(path)
+	fi, err := os.Lstat(path)
+	if err != nil {
+		if !c.ColorMissing || !errors.Is(err, fs.ErrNotExist) {
+			return "", err
+		}
+		ext := &c.MI
+		if ext.Empty() {
+			ext = &c.OR
+		}
+		return ext.Format(name), nil
+	}
+	return c.MatchInfo(path, fi).Format(name), nil
+}
+
+// FormatComponents returns path with each of its components colored by the
+// type of the file it names, so that for "src/main.go" "src" is colored
+// with DI and "main.go" by its extension. The separators are colored by
+// SeparatorColor, if set.
+// Every component is stat'd (without following symbolic links) and an
+// error is returned if any of them do not exist. The path is cleaned with
+// filepath.Clean first.
+func (c *LSColors) FormatComponents(path string) (string, error) {
+	path = filepath.Clean(path)
+	vol := filepath.VolumeName(path)
+	b := []byte(vol)
+	rest := path[len(vol):]
+	if len(rest) > 0 && os.IsPathSeparator(rest[0]) {
+		b = c.appendSeparator(b)
+		rest = rest[1:]
+	}
+	for i := 0; rest != ""; i++ {
+		name := rest
+		if j := strings.IndexByte(rest, filepath.Separator); j >= 0 {
+			name = rest[:j]
+		}
+		rest = rest[len(name):]
+		cur := path[:len(path)-len(rest)]
+		fi, err := os.Lstat(cur)
+		if err != nil {
+			return "", err
+		}
+		b = c.MatchInfo(cur, fi).AppendFormat(b, name)
+		if rest != "" {
+			b = c.appendSeparator(b)
+			rest = rest[1:]
+		}
+	}
+	return string(b), nil
+}
+
+// AppendPath appends path to b with its directory colored by DI and its
+// base name colored by the type of the file d and returns the extended
+// buffer. If SeparatorColor is set, each directory component is colored
+// separately and the separators between them with SeparatorColor.
+func (c *LSColors) AppendPath(b []byte, path string, d fs.DirEntry) []byte {
+	dir, base := filepath.Split(path)
+	if c.SeparatorColor == nil {
+		if dir != "" {
+			b = c.DI.AppendFormat(b, dir)
+		}
+	} else {
+		for dir != "" {
+			i := strings.IndexFunc(dir, func(r rune) bool {
+				return r < utf8.RuneSelf && os.IsPathSeparator(uint8(r))
+			})
+			if i > 0 {
+				b = c.DI.AppendFormat(b, dir[:i])
+			}
+			b = c.appendSeparator(b)
+			dir = dir[i+1:]
+		}
+	}
+	return c.MatchEntry(path, d).AppendFormat(b, base)
+}
+
+// appendSeparator appends a path separator colored by SeparatorColor to b.
+func (c *LSColors) appendSeparator(b []byte) []byte {
+	if c.SeparatorColor == nil || c.SeparatorColor.Seq == "" {
+		return append(b, filepath.Separator)
+	}
+	return c.SeparatorColor.AppendFormat(b, string(filepath.Separator))
+}
+
+// WriteEntry writes the name of d, colored by the type of the file at path,
+// to sb.
+func (c *LSColors) WriteEntry(sb *strings.Builder, path string, d fs.DirEntry) {
+	c.MatchEntry(path, d).WriteString(sb, d.Name())
+}
+
+// FormatClassify returns name colored by the type of the file at path
+// followed by an uncolored indicator of its type, like ls -F: '/' for
+// directories, '*' for executables, '@' for symbolic links, '|' for FIFOs
+// and '=' for sockets. The permission bits of regular files, which
+// fs.DirEntry.Type does not report, are read with d.Info so that
+// executables are colored with EX and marked as such.
+func (c *LSColors) FormatClassify(path, name string, d fs.DirEntry) string {
+	typ := d.Type()
+	if typ.IsRegular() {
+		typ = entryMode(typ, d)
+	}
+	ext, _ := c.match(path, d.Name(), typ, d)
+	return ext.Format(name) + classifySuffix(typ)
+}
+
+// FormatEntryWidth returns the name of d colored by its type and its width
+// in terminal columns, which counts wide East Asian characters as two
+// columns and combining characters as zero. It saves callers laying out
+// names in columns from having to strip the escape sequences to measure it.
+func (c *LSColors) FormatEntryWidth(path string, d fs.DirEntry) (string, int) {
+	name := d.Name()
+	return c.MatchEntry(path, d).Format(name), stringWidth(name)
+}
+
+// classifySuffix returns the ls -F indicator for a file of type typ.
+func classifySuffix(typ fs.FileMode) string {
+	switch {
+	case typ.IsDir():
+		return "/"
+	case typ&fs.ModeSymlink != 0:
+		return "@"
+	case typ&fs.ModeNamedPipe != 0:
+		return "|"
+	case typ&fs.ModeSocket != 0:
+		return "="
+	case typ.IsRegular() && typ&0111 != 0:
+		return "*"
+	}
+	return ""
+}
+
+// extKey is the key returned by match when an extension rule matched. The
+// full key is only built by callers that need it to avoid an allocation.
+const extKey = "*"
+
+// match returns the color for the file and the key of the matching rule.
+// The DirEntry d is optional and only used to check for broken links and
+// by ExtraMatch.
+func (c *LSColors) match(path, name string, typ fs.FileMode, d fs.DirEntry) (*ColorExtension, string) {
+	if c.ExtraMatch != nil && d != nil {
+		if e := c.ExtraMatch(path, d); e != nil {
+			return e, "extra"
+		}
+	}
+	return c.matchRules(path, name, typ, d)
+}
+
+// matchRules is match without ExtraMatch.
+func (c *LSColors) matchRules(path, name string, typ fs.FileMode, d fs.DirEntry) (*ColorExtension, string) {
+	if !c.finalized {
+		c.Finalize()
+	}
+	var ext *ColorExtension
+	var key string
+	switch {
+	case typ.IsDir():
+		// Directories are always searchable (executable) so they must
+		// never reach the EX case below, even if DI is not set.
+		if c.set(&c.DI, RuleDI) {
+			ext, key = &c.DI, "di"
+		}
+	case typ.IsRegular():
+		if typ&0111 != 0 && c.set(&c.EX, RuleEX) {
+			ext, key = &c.EX, "ex"
+		} else if c.set(&c.FI, RuleFI) {
+			ext, key = &c.FI, "fi"
+		}
+	case typ&fs.ModeSymlink != 0:
+		// TODO: make sure this matches the `ls` broken link logic
+		if c.set(&c.LN, RuleLN) {
+			ext, key = &c.LN, "ln"
+		}
+		if c.FollowLinks || c.set(&c.OR, RuleOR) {
+			fi, err := statLink(path, d)
+			if err != nil {
+				if c.set(&c.OR, RuleOR) {
+					ext, key = &c.OR, "or"
 				}
-				ls.Exts = append(ls.Exts, ColorExtension{
-					Ext: k[1:],
-					Seq: v,
-				})
-			} else {
-				invalid = append(invalid, s)
+			} else if c.FollowLinks && fi.IsDir() && c.set(&c.DI, RuleDI) {
+				ext, key = &c.DI, "di"
+			}
+		}
+	// Special files with an unset color fall back to NO below.
+	case typ&fs.ModeNamedPipe != 0:
+		if c.set(&c.PI, RulePI) {
+			ext, key = &c.PI, "pi"
+		}
+	case typ&fs.ModeSocket != 0:
+		if c.set(&c.SO, RuleSO) {
+			ext, key = &c.SO, "so"
+		} else if c.SocketsAsPipes && c.set(&c.PI, RulePI) {
+			ext, key = &c.PI, "pi"
+		}
+	case typ&fs.ModeCharDevice != 0: // must precede ModeDevice
+		if !c.WhiteoutColor.Empty() && d != nil {
+			if fi, err := d.Info(); err == nil && isWhiteout(fi) {
+				return &c.WhiteoutColor, "whiteout"
+			}
+		}
+		if c.set(&c.CD, RuleCD) {
+			ext, key = &c.CD, "cd"
+		}
+	case typ&fs.ModeDevice != 0:
+		if c.set(&c.BD, RuleBD) {
+			ext, key = &c.BD, "bd"
+		}
+	case typ&0111 != 0 && c.set(&c.EX, RuleEX):
+		ext, key = &c.EX, "ex"
+	default:
+		// TODO: GNU ls marks other files as broken links C_ORPHAN
+		if c.set(&c.OR, RuleOR) {
+			ext, key = &c.OR, "or"
+		}
+	}
+	if (name == "." || name == "..") && typ.IsDir() && !c.DotColor.Empty() {
+		return &c.DotColor, "dot"
+	}
+	if c.Normalize != nil {
+		name = c.Normalize(name)
+	}
+	if len(c.TypeRules) != 0 && c.Disabled&RuleExts == 0 {
+		if e := c.matchType(name, typ, d); e != nil {
+			return e, extKey
+		}
+	}
+	if typ.IsRegular() && c.Disabled&RuleExts == 0 && (ext != &c.EX || c.ExtensionsBeatExecutable ||
+		c.ScriptsBeatExecutable && isScript(path, d)) {
+		if len(c.Names) != 0 {
+			if e := c.matchName(name); e != nil {
+				return e, extKey
+			}
+		}
+		sfx := name
+		if c.MaxSuffixDepth > 0 {
+			sfx = suffixSegments(name, c.MaxSuffixDepth)
+		}
+		if e := c.matchExt(sfx); e != nil {
+			return e, extKey
+		}
+		if e := c.matchGlob(sfx); e != nil {
+			return e, e.Ext
+		}
+	}
+	if c.set(&c.HiddenColor, RuleHidden) && isHidden(name) {
+		return &c.HiddenColor, "hidden"
+	}
+	if ext == nil {
+		// Like GNU ls, use the "normal" color when there is no color
+		// for the file's type.
+		if c.set(&c.NO, RuleNO) {
+			return &c.NO, "no"
+		}
+		return &NoColor, ""
+	}
+	return ext, key
+}
+
+// setName adds ext to Names if it is an exact file name, which is the case
+// for extension rules that do not start with a '.'.
+func (c *LSColors) setName(ext, seq string) {
+	if ext == "" || ext[0] == '.' {
+		return
+	}
+	if c.Names == nil {
+		c.Names = make(map[string]*ColorExtension)
+	}
+	c.Names[ext] = &ColorExtension{Ext: ext, Seq: seq}
+}
+
+// matchName returns the color of name if it is in Names.
+func (c *LSColors) matchName(name string) *ColorExtension {
+	if e := c.Names[name]; e != nil {
+		return e
+	}
+	if c.FoldCase {
+		return c.matchNameFold(name)
+	}
+	return nil
+}
+
+// isHidden reports if name is a dotfile. The special "." and ".." entries
+// are not considered hidden.
+func isHidden(name string) bool {
+	return len(name) > 1 && name[0] == '.' && name != ".."
+}
+
+// matchExt returns the longest extension matching name. Exts must be sorted
+// (see Finalize).
+func (c *LSColors) matchExt(name string) *ColorExtension {
+	// TODO: could sort in reverse then use a binary search on length
+	// that way the first match is the longest
+
+	if c.FoldCase {
+		return c.matchExtFold(name)
+	}
+
+	// Find longest pattern
+	var sfx *ColorExtension
+	for i := range c.Exts {
+		e := &c.Exts[i]
+		if len(e.Ext) > len(name) {
+			break
+		}
+		if e.MatchExt(name) {
+			sfx = e
+		}
+	}
+	return sfx
+}
+
+func (c *LSColors) matchGlob(name string) *ColorExtension {
+	if c.FoldCase && len(c.Globs) != 0 {
+		return c.matchGlobFold(name)
+	}
+	for i := range c.Globs {
+		// The pattern was validated when it was added.
+		if ok, _ := path.Match(c.Globs[i].Ext, name); ok {
+			return &c.Globs[i]
+		}
+	}
+	return nil
+}
+
+// suffixSegments returns the last n dot separated segments of name,
+// including the leading '.', or name if it has n or fewer dots.
+func suffixSegments(name string, n int) string {
+	for i := len(name) - 1; i >= 0; i-- {
+		if name[i] == '.' {
+			if n--; n == 0 {
+				return name[i:]
 			}
 		}
 	}
+	return name
+}
+
+// isGlob reports if pattern should be matched with path.Match instead of as
+// a suffix, which is the case when it contains a '*' after the first byte.
+func isGlob(pattern string) bool {
+	return len(pattern) > 1 && strings.IndexByte(pattern[1:], '*') >= 0
+}
+
+// Finalize sorts Exts by length and name and removes duplicate extensions,
+// keeping the last, which is the order required for matching. It must be
+// called after modifying Exts directly. Palettes returned by ParseLSColors
+// are already finalized, and the matching methods call Finalize the first
+// time they are used, but since that modifies c it is not safe to do
+// concurrently: palettes built by hand should be finalized before they are
+// shared, which PaletteHolder.Store does. Calling Finalize more than once
+// has no effect.
+func (c *LSColors) Finalize() {
 	// Sort by length and name to make the order deterministic.
 	// Sorting by only length (which is all we really need) is
 	// 3x faster but the order is non-deterministic which
 	// makes comparing LSColors by the String method impossible.
-	sort.Slice(ls.Exts, func(i, j int) bool {
-		e1 := ls.Exts[i].Ext
-		e2 := ls.Exts[j].Ext
-		if len(e1) < len(e2) {
-			return true
+	//
+	// The sort is stable so that duplicate extensions remain in declaration
+	// order and the last one, which GNU ls uses, can be kept.
+	slices.SortStableFunc(c.Exts, compareExt)
+	for i := 1; i < len(c.Exts); i++ {
+		if c.Exts[i].Ext == c.Exts[i-1].Ext {
+			e := c.Exts[i-1]
+			e.Ext = "*" + e.Ext
+			c.shadowed = append(c.shadowed, e)
+		}
+	}
+	c.Exts = dedupeExts(c.Exts)
+	c.finalized = true
+}
+
+// dedupeExts removes duplicate extensions from the sorted exts keeping the
+// last occurrence of each.
+func dedupeExts(exts []ColorExtension) []ColorExtension {
+	if len(exts) < 2 {
+		return exts
+	}
+	j := 0
+	for i := 1; i < len(exts); i++ {
+		if exts[i].Ext != exts[j].Ext {
+			j++
+		}
+		exts[j] = exts[i]
+	}
+	clear(exts[j+1:])
+	return exts[:j+1]
+}
+
+// removeSpace returns s with all ASCII whitespace removed.
+func removeSpace(s string) string {
+	if !strings.ContainsAny(s, " \t\n\r\v\f") {
+		return s
+	}
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '\t', '\n', '\r', '\v', '\f':
+			return -1
 		}
-		if len(e1) > len(e2) {
+		return r
+	}, s)
+}
+
+func isDigit(c byte) bool { return '0' <= c && c <= '9' }
+
+func validSequence(s string) bool {
+	if len(s) == 0 || !isDigit(s[0]) {
+		return false
+	}
+	n := 1
+	for i := 1; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case isDigit(c):
+			n++
+			if n > 3 {
+				return false
+			}
+		case c == ';':
+			n = 0
+		default:
 			return false
 		}
-		return e1 < e2
+	}
+	return isDigit(s[len(s)-1])
+}
+
+// ParseOptions configures ParseLSColorsOptions.
+//
+// The value of every entry, including those of indicators such as "di" and
+// "ln", must be a valid SGR sequence whether or not Lenient is set. Values
+// that are not, such as the "ln=target" used by GNU ls to color symbolic
+// links like their targets, are reported as invalid and the entry is
+// skipped.
+type ParseOptions struct {
+	// Lenient removes whitespace from color sequences before they are
+	// validated so that sequences like "01; 34", which are produced by some
+	// broken exporters, are accepted.
+	Lenient bool
+}
+
+func ParseLSColors(clrs string) (*LSColors, error) {
+	return ParseLSColorsOptions(clrs, ParseOptions{})
+}
+
+// ParseLSColorsBytes is like ParseLSColors but parses b, which avoids having
+// to convert large palettes read from a file to a string. The returned
+// LSColors does not reference b.
+func ParseLSColorsBytes(b []byte) (*LSColors, error) {
+	if len(b) == 0 {
+		return ParseLSColors("")
+	}
+	// The strings retained by ls are copied before returning.
+	ls, err := ParseLSColors(unsafe.String(&b[0], len(b)))
+	if ls != nil {
+		ls.cloneStrings()
+	}
+	return ls, err
+}
+
+// cloneStrings copies all the strings referenced by c into a single new
+// allocation.
+func (c *LSColors) cloneStrings() {
+	n := 0
+	c.eachRule(func(e *ColorExtension) {
+		n += len(e.Ext) + len(e.Seq)
+	})
+	var w strings.Builder
+	w.Grow(n)
+	c.eachRule(func(e *ColorExtension) {
+		w.WriteString(e.Ext)
+		w.WriteString(e.Seq)
+	})
+	s := w.String()
+	c.eachRule(func(e *ColorExtension) {
+		e.Ext, s = s[:len(e.Ext)], s[len(e.Ext):]
+		e.Seq, s = s[:len(e.Seq)], s[len(e.Seq):]
 	})
+	for i, v := range c.invalid {
+		c.invalid[i] = strings.Clone(v)
+	}
+	c.LeftCode = strings.Clone(c.LeftCode)
+	c.RightCode = strings.Clone(c.RightCode)
+	c.EndCode = strings.Clone(c.EndCode)
+	for i := range c.shadowed {
+		e := &c.shadowed[i]
+		e.Ext, e.Seq = strings.Clone(e.Ext), strings.Clone(e.Seq)
+	}
+	if c.Names != nil {
+		// Parsed names are always also extension rules.
+		names := make(map[string]*ColorExtension, len(c.Names))
+		for _, e := range c.Exts {
+			if _, ok := c.Names[e.Ext]; ok {
+				names[e.Ext] = &ColorExtension{Ext: e.Ext, Seq: e.Seq}
+			}
+		}
+		c.Names = names
+	}
+	for i, ext := range c.extOrder {
+		if j, ok := slices.BinarySearchFunc(c.Exts, ColorExtension{Ext: ext}, compareExt); ok {
+			c.extOrder[i] = c.Exts[j].Ext
+		} else {
+			c.extOrder[i] = strings.Clone(ext)
+		}
+	}
+}
+
+// eachColor calls fn with a description and the color of every rule of c,
+// including the ones that are not part of LS_COLORS. Colors stored by
+// value in maps are written back if fn modifies them. Every field of
+// LSColors that holds a sequence must be visited here so that it is
+// checked by Validate and rewritten by mapSeqs.
+func (c *LSColors) eachColor(fn func(key string, e *ColorExtension)) {
+	for i, e := range c.indicators() {
+		fn(indicatorKeys[i], e)
+	}
+	for i := range c.Exts {
+		fn("*"+c.Exts[i].Ext, &c.Exts[i])
+	}
+	for i := range c.Globs {
+		fn(c.Globs[i].Ext, &c.Globs[i])
+	}
+	for _, name := range sortedKeys(c.Names) {
+		if e := c.Names[name]; e != nil {
+			fn("Names["+strconv.Quote(name)+"]", e)
+		}
+	}
+	fn("HiddenColor", &c.HiddenColor)
+	fn("DotColor", &c.DotColor)
+	fn("OwnedColor", &c.OwnedColor)
+	fn("OtherColor", &c.OtherColor)
+	fn("WhiteoutColor", &c.WhiteoutColor)
+	if c.SeparatorColor != nil {
+		fn("SeparatorColor", c.SeparatorColor)
+	}
+	if g := c.Git; g != nil {
+		fn("Git.Modified", &g.Modified)
+		fn("Git.Untracked", &g.Untracked)
+		fn("Git.Ignored", &g.Ignored)
+		fn("Git.Staged", &g.Staged)
+	}
+	for i := range c.DepthColors {
+		fn("DepthColors["+strconv.Itoa(i)+"]", &c.DepthColors[i])
+	}
+	for i := range c.TypeRules {
+		fn("TypeRules["+strconv.Itoa(i)+"]", &c.TypeRules[i].ColorExtension)
+	}
+	for i := range c.SizeRules {
+		fn("SizeRules["+strconv.Itoa(i)+"]", &c.SizeRules[i].ColorExtension)
+	}
+	for i := range c.AgeRules {
+		fn("AgeRules["+strconv.Itoa(i)+"]", &c.AgeRules[i].ColorExtension)
+	}
+	for i := range c.DeviceRules {
+		fn("DeviceRules["+strconv.Itoa(i)+"]", &c.DeviceRules[i].ColorExtension)
+	}
+	for _, name := range sortedKeys(c.ShebangRules) {
+		e := c.ShebangRules[name]
+		fn("ShebangRules["+strconv.Quote(name)+"]", &e)
+		if e != c.ShebangRules[name] {
+			c.ShebangRules[name] = e
+		}
+	}
+}
+
+// sortedKeys returns the keys of m in sorted order.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// eachRule calls fn with every indicator, extension and glob of c.
+func (c *LSColors) eachRule(fn func(e *ColorExtension)) {
+	for _, e := range c.indicators() {
+		fn(e)
+	}
+	for i := range c.Exts {
+		fn(&c.Exts[i])
+	}
+	for i := range c.Globs {
+		fn(&c.Globs[i])
+	}
+}
+
+// ParseLSColorsOptions is like ParseLSColors but accepts options that
+// control how clrs is parsed.
+func ParseLSColorsOptions(clrs string, opts ParseOptions) (*LSColors, error) {
+	if clrs == "" {
+		return nil, ErrEmpty
+	}
+	var invalid []string
+	var ls LSColors
+	// Count the entries once up front so that Exts, which is allocated
+	// lazily, can be sized for every entry that follows the first
+	// extension.
+	total := strings.Count(clrs, ":") + 1
+	for parsed := 0; len(clrs) > 0; parsed++ {
+		var s string
+		if i := strings.IndexByte(clrs, ':'); i >= 0 {
+			s = clrs[:i]
+			clrs = clrs[i+1:]
+		} else {
+			s = clrs // EOF
+			clrs = ""
+		}
+		k, v, ok := strings.Cut(s, "=")
+		if !ok || k == "" || v == "" {
+			invalid = append(invalid, s)
+			continue
+		}
+		if opts.Lenient {
+			v = removeSpace(v)
+		}
+		if code := ls.code(k); code != nil {
+			d, ok := decodeEscapes(v)
+			if !ok {
+				invalid = append(invalid, s)
+				continue
+			}
+			*code = d
+			continue
+		}
+		if e := ls.indicator(k); e != nil {
+			if !validSequence(v) {
+				invalid = append(invalid, s)
+				continue
+			}
+			*e = ColorExtension{Ext: k, Seq: v}
+			continue
+		}
+		if isGlob(k) {
+			if _, err := path.Match(k, ""); err != nil || !validSequence(v) {
+				invalid = append(invalid, s)
+				continue
+			}
+			// Later declarations of a pattern replace earlier ones.
+			if i := slices.IndexFunc(ls.Globs, func(e ColorExtension) bool {
+				return e.Ext == k
+			}); i >= 0 {
+				ls.shadowed = append(ls.shadowed, ls.Globs[i])
+				ls.Globs[i].Seq = v
+			} else {
+				ls.Globs = append(ls.Globs, ColorExtension{Ext: k, Seq: v})
+			}
+			continue
+		}
+		if !strings.HasPrefix(k, "*") || !validSequence(v) {
+			invalid = append(invalid, s)
+			continue
+		}
+		if ls.Exts == nil {
+			// Lazily allocate
+			n := total - parsed
+			ls.Exts = make([]ColorExtension, 0, n)
+			ls.extOrder = make([]string, 0, n)
+		}
+		ls.Exts = append(ls.Exts, ColorExtension{
+			Ext: k[1:],
+			Seq: v,
+		})
+		ls.extOrder = append(ls.extOrder, k[1:])
+		ls.setName(k[1:], v)
+	}
+	ls.Finalize()
+	if len(invalid) > 0 {
+		ls.invalid = invalid
+		return &ls, fmt.Errorf("lscolors: unparsable value for LS_COLORS "+
+			"environment variable(s): %q", invalid)
+	}
+	return &ls, nil
+}
+
+// ParseLSColorsFunc parses the LS_COLORS value clrs and calls fn with the
+// key and sequence of each valid entry, in order, without building an
+// LSColors. Keys are the same as those passed to the Range callback: the
+// indicator key ("di"), extension pattern ("*.go") or glob ("*.min.*").
+// The escape codes "lc", "rc" and "ec" are passed to fn decoded, as they
+// are stored in LeftCode, RightCode and EndCode by ParseLSColors.
+// Duplicates are passed to fn as they appear. Parsing stops if fn returns
+// an error, which is returned. Invalid entries are skipped and reported in
+// the returned error once parsing is complete.
+func ParseLSColorsFunc(clrs string, fn func(key, seq string) error) error {
+	if clrs == "" {
+		return ErrEmpty
+	}
+	var invalid []string
+	for len(clrs) > 0 {
+		var s string
+		if i := strings.IndexByte(clrs, ':'); i >= 0 {
+			s, clrs = clrs[:i], clrs[i+1:]
+		} else {
+			s, clrs = clrs, ""
+		}
+		k, v, ok := strings.Cut(s, "=")
+		switch {
+		case !ok || k == "" || v == "":
+			ok = false
+		case isCodeKey(k):
+			v, ok = decodeEscapes(v)
+		case !validSequence(v):
+			ok = false
+		case slices.Contains(indicatorKeys[:], k):
+		case isGlob(k):
+			_, err := path.Match(k, "")
+			ok = err == nil
+		default:
+			ok = strings.HasPrefix(k, "*")
+		}
+		if !ok {
+			invalid = append(invalid, s)
+			continue
+		}
+		if err := fn(k, v); err != nil {
+			return err
+		}
+	}
+	if len(invalid) > 0 {
+		return fmt.Errorf("lscolors: unparsable value for LS_COLORS "+
+			"environment variable(s): %q", invalid)
+	}
+	return nil
+}
+
+// ParseLSColorsFiltered is like ParseLSColors but only parses the named
+// indicators in keys, such as "di" and "ln". Everything else, including all
+// extension and glob rules, is skipped without being validated or
+// allocated, so the returned LSColors only colors files using the parsed
+// indicators.
+func ParseLSColorsFiltered(clrs string, keys ...string) (*LSColors, error) {
+	if clrs == "" {
+		return nil, ErrEmpty
+	}
+	var invalid []string
+	ls := LSColors{finalized: true}
+	for len(clrs) > 0 {
+		var s string
+		if i := strings.IndexByte(clrs, ':'); i >= 0 {
+			s, clrs = clrs[:i], clrs[i+1:]
+		} else {
+			s, clrs = clrs, ""
+		}
+		k, v, _ := strings.Cut(s, "=")
+		if !slices.Contains(keys, k) {
+			continue
+		}
+		e := ls.indicator(k)
+		if e == nil {
+			continue
+		}
+		if !validSequence(v) {
+			invalid = append(invalid, s)
+			continue
+		}
+		*e = ColorExtension{Ext: k, Seq: v}
+	}
 	if len(invalid) > 0 {
+		ls.invalid = invalid
 		return &ls, fmt.Errorf("lscolors: unparsable value for LS_COLORS "+
 			"environment variable(s): %q", invalid)
 	}
@@ -439,7 +2058,7 @@ func ParseLSColors(clrs string) (*LSColors, error) {
 func NewLSColors() (*LSColors, error) {
 	clrs, ok := os.LookupEnv("LS_COLORS")
 	if !ok {
-		return nil, errors.New("ls_colors: LS_COLORS not set")
+		return nil, ErrNotSet
 	}
 	return ParseLSColors(clrs)
 }
@@ -1,7 +1,16 @@
 package lscolors
 
 import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"math/rand"
 	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
 	"testing"
 )
@@ -16,6 +25,16 @@ func init() {
 	benchLS = ls
 }
 
+// equalColors reports if a and b are equal ignoring the order in which
+// their extensions were declared and the rules they shadowed, which are
+// not serialized.
+func equalColors(a, b *LSColors) bool {
+	a, b = a.Clone(), b.Clone()
+	a.extOrder, b.extOrder = nil, nil
+	a.shadowed, b.shadowed = nil, nil
+	return reflect.DeepEqual(a, b)
+}
+
 func TestParseLSColors(t *testing.T) {
 	colors := []string{
 		"bd=0;38;2;138;190;183;48;2;51;51;51",
@@ -89,6 +108,238 @@ func TestParseLSColors(t *testing.T) {
 	}
 }
 
+func TestParseLSColorsLenient(t *testing.T) {
+	const colors = "di=01; 34:*.go=0 ;32"
+	if _, err := ParseLSColors(colors); err == nil {
+		t.Errorf("ParseLSColors(%q): expected error", colors)
+	}
+	ls, err := ParseLSColorsOptions(colors, ParseOptions{Lenient: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ls.DI.Seq != "01;34" {
+		t.Errorf("DI = %q; want: %q", ls.DI.Seq, "01;34")
+	}
+	if len(ls.Exts) != 1 || ls.Exts[0].Seq != "0;32" {
+		t.Errorf("Exts = %+v; want: [{.go 0;32}]", ls.Exts)
+	}
+	// Lenient mode still rejects sequences that are invalid without spaces.
+	if _, err := ParseLSColorsOptions("di=01;x4", ParseOptions{Lenient: true}); err == nil {
+		t.Error("expected error for invalid sequence")
+	}
+
+	// Indicator values must be sequences in both modes.
+	for _, opts := range []ParseOptions{{}, {Lenient: true}} {
+		ls, err := ParseLSColorsOptions("ln=target:di=01;34", opts)
+		if err == nil {
+			t.Errorf("%+v: ParseLSColorsOptions(ln=target): expected error", opts)
+		}
+		if ls.LN.Seq != "" || ls.DI.Seq != "01;34" {
+			t.Errorf("%+v: LN = %q, DI = %q; want: %q, %q", opts, ls.LN.Seq, ls.DI.Seq, "", "01;34")
+		}
+		if !reflect.DeepEqual(ls.Invalid(), []string{"ln=target"}) {
+			t.Errorf("%+v: Invalid() = %q; want: %q", opts, ls.Invalid(), []string{"ln=target"})
+		}
+	}
+}
+
+func TestParseLSColorsFiltered(t *testing.T) {
+	const colors = "di=01;34:ln=01;36:ex=01;32:*.tar=01;31:*.min.*=02:fi=bad"
+	ls, err := ParseLSColorsFiltered(colors, "di", "ln", "*.tar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ls.DI.Seq != "01;34" || ls.LN.Seq != "01;36" {
+		t.Errorf("DI, LN = %q, %q; want: %q, %q", ls.DI.Seq, ls.LN.Seq, "01;34", "01;36")
+	}
+	if !ls.EX.Empty() || ls.Exts != nil || ls.Globs != nil {
+		t.Errorf("skipped keys were parsed: %+v", ls)
+	}
+	if s := ls.String(); s != "di=01;34:ln=01;36" {
+		t.Errorf("String() = %q; want: %q", s, "di=01;34:ln=01;36")
+	}
+	if e := ls.MatchEntry("a.tar", testDirEntry{name: "a.tar"}); e != &NoColor {
+		t.Errorf("MatchEntry(a.tar) = %+v; want: NoColor", e)
+	}
+
+	if _, err := ParseLSColorsFiltered(colors, "fi"); err == nil {
+		t.Error("expected an error for an invalid requested indicator")
+	}
+	if _, err := ParseLSColorsFiltered("", "di"); err != ErrEmpty {
+		t.Errorf("error = %v; want: %v", err, ErrEmpty)
+	}
+}
+
+func TestParseLSColorsFunc(t *testing.T) {
+	const colors = "di=01;34:ln=01;36:bad:xx=1:*.go=32:ex=0m:*.min.*=02:*.tar=01;31"
+	type entry struct{ key, seq string }
+	var got []entry
+	err := ParseLSColorsFunc(colors, func(key, seq string) error {
+		got = append(got, entry{key, seq})
+		return nil
+	})
+	if err == nil || !strings.Contains(err.Error(), `"bad"`) || !strings.Contains(err.Error(), `"ex=0m"`) {
+		t.Errorf("expected error reporting the invalid entries: %v", err)
+	}
+
+	ls, _ := ParseLSColors(colors)
+	var want []entry
+	ls.Range(func(key, seq string) bool {
+		want = append(want, entry{key, seq})
+		return true
+	})
+	less := func(a, b entry) int { return strings.Compare(a.key, b.key) }
+	slices.SortFunc(got, less)
+	slices.SortFunc(want, less)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseLSColorsFunc:\ngot:  %q\nwant: %q", got, want)
+	}
+
+	// Escape codes are accepted like they are by ParseLSColors.
+	got = nil
+	err = ParseLSColorsFunc(`lc=\e[:rc=m:ec=\x:di=1`, func(key, seq string) error {
+		got = append(got, entry{key, seq})
+		return nil
+	})
+	if err == nil || !strings.Contains(err.Error(), `"ec=\\x"`) {
+		t.Errorf("expected error reporting the invalid escape: %v", err)
+	}
+	if want := []entry{{"lc", "\x1b["}, {"rc", "m"}, {"di", "1"}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseLSColorsFunc:\ngot:  %q\nwant: %q", got, want)
+	}
+
+	stop := errors.New("stop")
+	n := 0
+	err = ParseLSColorsFunc(colors, func(key, seq string) error {
+		n++
+		return stop
+	})
+	if err != stop || n != 1 {
+		t.Errorf("ParseLSColorsFunc = %v after %d calls; want: %v after 1", err, n, stop)
+	}
+}
+
+func TestParseLSColorsDuplicates(t *testing.T) {
+	ls, err := ParseLSColors("*.go=1:*.c=2:*.go=3:.git*=4:.git*=5")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []ColorExtension{{Ext: ".c", Seq: "2"}, {Ext: ".go", Seq: "3"}}
+	if !reflect.DeepEqual(ls.Exts, want) {
+		t.Errorf("Exts = %+v; want: %+v", ls.Exts, want)
+	}
+	want = []ColorExtension{{Ext: ".git*", Seq: "5"}}
+	if !reflect.DeepEqual(ls.Globs, want) {
+		t.Errorf("Globs = %+v; want: %+v", ls.Globs, want)
+	}
+}
+
+func TestPatchString(t *testing.T) {
+	tests := []struct {
+		base, colors, patch string
+	}{
+		{"di=01;34:*.go=32", "di=01;34:*.go=32", ""},
+		{"di=01;34:*.go=32", "di=01;35:*.go=32", "di=01;35"},
+		{"di=01;34:*.go=32", "di=01;34:ln=36:*.go=33:*.c=32", "ln=36:*.c=32:*.go=33"},
+		{"di=01;34", "di=01;34:.git*=1", ".git*=1"},
+		{"di=01;34:.git*=1", "di=01;34:.git*=2", ".git*=2"},
+	}
+	for _, x := range tests {
+		base, err := ParseLSColors(x.base)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ls, err := ParseLSColors(x.colors)
+		if err != nil {
+			t.Fatal(err)
+		}
+		patch := ls.PatchString(base)
+		if patch != x.patch {
+			t.Errorf("PatchString(%q, %q) = %q; want: %q", x.colors, x.base, patch, x.patch)
+		}
+		if patch == "" {
+			continue
+		}
+		applied, err := ParseLSColors(base.String() + ":" + patch)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !equalColors(applied, ls) {
+			t.Errorf("applying patch %q to %q = %q; want: %q", patch, x.base, applied, ls)
+		}
+	}
+}
+
+func TestParseLSColorsBytes(t *testing.T) {
+	tests := []string{
+		"",
+		"di=01;34",
+		"di=01;34:*.go=32:.git*=1:bad:*.x=y",
+		"*.go=1:*.go=2:.git*=1:.git*=2", // shadowed rules
+		"di=01;34:rc=m:lc=X[:ec=E",      // escape codes
+		strings.Join(hugeLSCOLOR, ":"),
+	}
+	for _, colors := range tests {
+		want, wantErr := ParseLSColors(colors)
+		b := []byte(colors)
+		got, err := ParseLSColorsBytes(b)
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("ParseLSColorsBytes(%.32q) = %+v; want: %+v", colors, got, want)
+		}
+		if fmt.Sprint(err) != fmt.Sprint(wantErr) {
+			t.Errorf("ParseLSColorsBytes(%.32q) error = %v; want: %v", colors, err, wantErr)
+		}
+		// The result must not alias b
+		for i := range b {
+			b[i] = 'X'
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("ParseLSColorsBytes(%.32q): result changed after modifying input", colors)
+		}
+	}
+}
+
+func TestInvalid(t *testing.T) {
+	ls, err := ParseLSColors("di=01;34:bad:xx=1:*.go=3x:*.c=32:ln=:fi=0")
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	want := []string{"bad", "xx=1", "*.go=3x", "ln="}
+	if got := ls.Invalid(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Invalid() = %q; want: %q", got, want)
+	}
+	if s := ls.String(); s != "di=01;34:fi=0:*.c=32" {
+		t.Errorf("String() = %q; want: %q", s, "di=01;34:fi=0:*.c=32")
+	}
+
+	ls, err = ParseLSColors("di=01;34")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := ls.Invalid(); len(got) != 0 {
+		t.Errorf("Invalid() = %q; want: []", got)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	if _, err := ParseLSColors(""); !errors.Is(err, ErrEmpty) {
+		t.Errorf("ParseLSColors(\"\") = %v; want: %v", err, ErrEmpty)
+	}
+	if _, err := ParseLSColorsBytes(nil); !errors.Is(err, ErrEmpty) {
+		t.Errorf("ParseLSColorsBytes(nil) = %v; want: %v", err, ErrEmpty)
+	}
+
+	t.Setenv("LS_COLORS", "")
+	os.Unsetenv("LS_COLORS")
+	if _, err := NewLSColors(); !errors.Is(err, ErrNotSet) {
+		t.Errorf("NewLSColors() = %v; want: %v", err, ErrNotSet)
+	}
+	t.Setenv("LS_COLORS", "")
+	if _, err := NewLSColors(); !errors.Is(err, ErrEmpty) {
+		t.Errorf("NewLSColors() = %v; want: %v", err, ErrEmpty)
+	}
+}
+
 func TestParseLSColorsStringAllocs(t *testing.T) {
 	ls, err := ParseLSColors(strings.Join(hugeLSCOLOR, ":"))
 	if err != nil {
@@ -102,6 +353,365 @@ func TestParseLSColorsStringAllocs(t *testing.T) {
 	}
 }
 
+func TestLSColorsString(t *testing.T) {
+	tests := []string{
+		"di=01;34",
+		"di=01;34:fi=0:ln=01;36:pi=33:so=01;35:bd=01;33:cd=01;33:or=31:mi=31:ex=01;32:tw=30;42:no=0:st=37;44:ow=34;42:rs=0",
+		"*.gz=01;31",
+		"ow=34;42:*.gz=01;31:*.tar.gz=01;32",
+		"*.gz=01;31:.git*=1:*.min.*=2",
+		strings.Join(hugeLSCOLOR, ":"),
+	}
+	for _, colors := range tests {
+		ls, err := ParseLSColors(colors)
+		if err != nil {
+			t.Fatal(err)
+		}
+		s := ls.String()
+		if n := ls.SerializedLen(); n != len(s) {
+			t.Errorf("SerializedLen() = %d; want: %d", n, len(s))
+		}
+		ls2, err := ParseLSColors(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !equalColors(ls, ls2) {
+			t.Errorf("ParseLSColors(%q).String() did not round-trip:\ngot:  %+v\nwant: %+v",
+				colors, ls2, ls)
+		}
+	}
+	if s := new(LSColors).String(); s != "" {
+		t.Errorf("LSColors{}.String() = %q; want: %q", s, "")
+	}
+	if n := new(LSColors).SerializedLen(); n != 0 {
+		t.Errorf("LSColors{}.SerializedLen() = %d; want: %d", n, 0)
+	}
+}
+
+func TestLSColorsStringGrow(t *testing.T) {
+	ls, err := ParseLSColors(strings.Join(hugeLSCOLOR, ":"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var w strings.Builder
+	w.Grow(ls.SerializedLen())
+	n := w.Cap()
+	ls.writeString(&w)
+	if w.Cap() != n {
+		t.Errorf("writeString grew the buffer: cap = %d; want: %d", w.Cap(), n)
+	}
+	if s := w.String(); s != ls.String() {
+		t.Errorf("writeString = %q; want: %q", s, ls.String())
+	}
+}
+
+func TestRange(t *testing.T) {
+	ls, err := ParseLSColors("*.go=32:fi=0:.git*=1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var keys []string
+	ls.Range(func(key, seq string) bool {
+		keys = append(keys, key+"="+seq)
+		return true
+	})
+	if s := strings.Join(keys, ":"); s != ls.String() {
+		t.Errorf("Range = %q; want: %q", s, ls.String())
+	}
+	n := 0
+	ls.Range(func(key, seq string) bool {
+		n++
+		return false
+	})
+	if n != 1 {
+		t.Errorf("Range called fn %d times after it returned false; want: 1", n)
+	}
+}
+
+func TestEntries(t *testing.T) {
+	const colors = "di=01;34:ln=01;36:*.c=33:*.go=32:.git*=1:*.min.*=2"
+	ls, err := ParseLSColors(colors)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []ColorExtension{
+		{Ext: "di", Seq: "01;34"},
+		{Ext: "ln", Seq: "01;36"},
+		{Ext: "*.c", Seq: "33"},
+		{Ext: "*.go", Seq: "32"},
+		{Ext: ".git*", Seq: "1"},
+		{Ext: "*.min.*", Seq: "2"},
+	}
+	entries := ls.Entries()
+	if !reflect.DeepEqual(entries, want) {
+		t.Errorf("Entries() = %+v; want: %+v", entries, want)
+	}
+	keys := make([]string, len(entries))
+	for i, e := range entries {
+		keys[i] = e.Ext + "=" + e.Seq
+	}
+	if s := strings.Join(keys, ":"); s != colors {
+		t.Errorf("Entries() joined = %q; want: %q", s, colors)
+	}
+	if entries := (&LSColors{}).Entries(); len(entries) != 0 {
+		t.Errorf("Entries() = %+v; want none", entries)
+	}
+}
+
+func TestSet(t *testing.T) {
+	ls, err := ParseLSColors("*.c=1:*.go=2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, x := range [][2]string{
+		{"di", "01;34"},
+		{"*.a", "3"},
+		{"*.go", "4"},
+		{"*.tar.gz", "5"},
+		{".git*", "6"},
+	} {
+		if err := ls.Set(x[0], x[1]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want := "di=01;34:*.a=3:*.c=1:*.go=4:*.tar.gz=5:.git*=6"
+	if s := ls.String(); s != want {
+		t.Errorf("String() = %q; want: %q", s, want)
+	}
+
+	for _, x := range [][2]string{
+		{"di", "01;34m\x1b[2J"}, // clear screen
+		{"*.go", "1\x1b[H"},     // cursor home
+		{"*.go", ""},
+		{"xx", "1"},
+		{"*", "1"},
+		{"[*", "1"},
+	} {
+		if err := ls.Set(x[0], x[1]); err == nil {
+			t.Errorf("Set(%q, %q): expected error", x[0], x[1])
+		}
+	}
+	if s := ls.String(); s != want {
+		t.Errorf("String() = %q after invalid Set; want: %q", s, want)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	ls, err := ParseLSColors(strings.Join(hugeLSCOLOR, ":"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ls.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	ls.DI.Seq = "0m\x1b[2J\x1b[H" // clear screen and move the cursor
+	ls.Exts[0].Seq = "1\x1b]0;title\a"
+	err = ls.Validate()
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	for _, s := range []string{`"di"`, strconv.Quote("*" + ls.Exts[0].Ext)} {
+		if !strings.Contains(err.Error(), s) {
+			t.Errorf("Validate() = %q; want it to mention %s", err, s)
+		}
+	}
+}
+
+func TestValidateCorrupt(t *testing.T) {
+	ls, err := ParseLSColors("*.gz=01;31:*.tar.gz=01;32:*.min.*=02")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ls.Exts = append(ls.Exts,
+		ColorExtension{Ext: ".a"}, // empty Seq and out of order
+		ColorExtension{Seq: "01"}, // empty Ext
+	)
+	ls.Globs[0].Seq = ""
+	err = ls.Validate()
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	for _, s := range []string{`"*.a"`, "empty extension", `"*.min.*"`, "not sorted"} {
+		if !strings.Contains(err.Error(), s) {
+			t.Errorf("Validate() = %q; want it to mention %s", err, s)
+		}
+	}
+
+	// Unfinalized palettes are sorted when they are first used.
+	ls = &LSColors{Exts: []ColorExtension{{Ext: ".tar.gz", Seq: "1"}, {Ext: ".gz", Seq: "2"}}}
+	if err := ls.Validate(); err != nil {
+		t.Errorf("Validate() = %v; want: nil", err)
+	}
+}
+
+func TestValidateExtraColors(t *testing.T) {
+	const bad = "1\x1b[2J"
+	ls := &LSColors{
+		DotColor:       ColorExtension{Seq: bad},
+		WhiteoutColor:  ColorExtension{Seq: bad},
+		SeparatorColor: &ColorExtension{Seq: bad},
+		Names:          map[string]*ColorExtension{"Makefile": {Ext: "Makefile", Seq: bad}},
+		DepthColors:    []ColorExtension{{Seq: "1"}, {Seq: bad}},
+		SizeRules:      []SizeRule{{Min: 1, ColorExtension: ColorExtension{Seq: bad}}},
+		DeviceRules:    []DeviceRule{{Major: 1, ColorExtension: ColorExtension{Seq: bad}}},
+		ShebangRules:   map[string]ColorExtension{"python": {Seq: bad}},
+		Git:            &GitColors{Staged: ColorExtension{Seq: bad}},
+	}
+	err := ls.Validate()
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	for _, s := range []string{
+		`"DotColor"`, `"WhiteoutColor"`, `"SeparatorColor"`,
+		`Names[\"Makefile\"]`, `"DepthColors[1]"`, `"SizeRules[0]"`,
+		`"DeviceRules[0]"`, `ShebangRules[\"python\"]`, `"Git.Staged"`,
+	} {
+		if !strings.Contains(err.Error(), s) {
+			t.Errorf("Validate() = %q; want it to mention %s", err, s)
+		}
+	}
+	if strings.Contains(err.Error(), "DepthColors[0]") {
+		t.Errorf("Validate() = %q; want no error for a valid sequence", err)
+	}
+}
+
+func TestAddExtensions(t *testing.T) {
+	ls, err := ParseLSColors("di=01;34:*.go=32:*.tar.gz=01;31")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ls.AddExtensions([]ColorExtension{
+		{Ext: ".zip", Seq: "01;31"},
+		{Ext: "*.go", Seq: "33"}, // replaces existing
+		{Ext: ".gz", Seq: "31"},
+		{Ext: ".zip", Seq: "35"}, // duplicate: last wins
+		{Ext: "*", Seq: "1"},     // ignored
+	})
+	const want = "di=01;34:*.go=33:*.gz=31:*.zip=35:*.tar.gz=01;31"
+	if s := ls.String(); s != want {
+		t.Errorf("String() = %q; want: %q", s, want)
+	}
+	if err := ls.Validate(); err != nil {
+		t.Error(err)
+	}
+	if e := ls.MatchEntry("a.tar.gz", testDirEntry{name: "a.tar.gz"}); e.Seq != "01;31" {
+		t.Errorf("MatchEntry(a.tar.gz) = %q; want: %q", e.Seq, "01;31")
+	}
+}
+
+func TestExtensionsInOrder(t *testing.T) {
+	ls, err := ParseLSColorsBytes([]byte("*.tar.gz=1:*.gz=2:*.go=3:*.c=4:*.gz=5"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ls.Set("*.a", "6"); err != nil {
+		t.Fatal(err)
+	}
+	ls.AddExtensions([]ColorExtension{{Ext: ".zip", Seq: "7"}})
+	ls.Exts = append(ls.Exts, ColorExtension{Ext: ".b", Seq: "8"})
+	ls.Finalize()
+
+	keys := func(exts []ColorExtension) string {
+		var a []string
+		for _, e := range exts {
+			a = append(a, e.Ext+"="+e.Seq)
+		}
+		return strings.Join(a, ":")
+	}
+	const declared = ".tar.gz=1:.gz=5:.go=3:.c=4:.a=6:.zip=7:.b=8"
+	if s := keys(ls.ExtensionsInOrder()); s != declared {
+		t.Errorf("ExtensionsInOrder() = %q; want: %q", s, declared)
+	}
+	const sorted = ".a=6:.b=8:.c=4:.go=3:.gz=5:.zip=7:.tar.gz=1"
+	if s := keys(ls.Exts); s != sorted {
+		t.Errorf("Exts = %q; want: %q", s, sorted)
+	}
+	if e := ls.MatchEntry("x.tar.gz", testDirEntry{name: "x.tar.gz"}); e.Seq != "1" {
+		t.Errorf("MatchEntry(x.tar.gz) = %q; want: %q", e.Seq, "1")
+	}
+
+	ls.Disable("*.go")
+	const disabled = ".tar.gz=1:.gz=5:.c=4:.a=6:.zip=7:.b=8"
+	if s := keys(ls.Clone().ExtensionsInOrder()); s != disabled {
+		t.Errorf("ExtensionsInOrder() = %q; want: %q", s, disabled)
+	}
+}
+
+func TestExtensionLess(t *testing.T) {
+	ls, err := ParseLSColors("*.tar.gz=1:*.go=2:*.c=3:*.gz=4:*README=5:*.h=6:*.tar=7")
+	if err != nil {
+		t.Fatal(err)
+	}
+	exts := slices.Clone(ls.Exts)
+	rand.New(rand.NewSource(1)).Shuffle(len(exts), func(i, j int) {
+		exts[i], exts[j] = exts[j], exts[i]
+	})
+	sorted := slices.Clone(exts)
+	slices.SortFunc(sorted, CompareExtensions)
+	if !slices.Equal(extNames(sorted), extNames(ls.Exts)) {
+		t.Errorf("SortFunc(CompareExtensions) = %q; want: %q", extNames(sorted), extNames(ls.Exts))
+	}
+	sorted = slices.Clone(exts)
+	sort.Slice(sorted, func(i, j int) bool { return ExtensionLess(sorted[i], sorted[j]) })
+	if !slices.Equal(extNames(sorted), extNames(ls.Exts)) {
+		t.Errorf("sort.Slice(ExtensionLess) = %q; want: %q", extNames(sorted), extNames(ls.Exts))
+	}
+	if ExtensionLess(ls.Exts[0], ls.Exts[0]) {
+		t.Error("ExtensionLess(a, a) = true; want: false")
+	}
+}
+
+func extNames(exts []ColorExtension) []string {
+	names := make([]string, len(exts))
+	for i, e := range exts {
+		names[i] = e.Ext
+	}
+	return names
+}
+
+func TestFinalize(t *testing.T) {
+	// Deliberately unsorted and with a duplicate
+	ls := &LSColors{
+		FI: ColorExtension{Ext: "fi", Seq: "0"},
+		Exts: []ColorExtension{
+			{Ext: ".tar.gz", Seq: "1"},
+			{Ext: ".gz", Seq: "2"},
+			{Ext: "z", Seq: "3"},
+			{Ext: ".gz", Seq: "4"},
+		},
+	}
+	tests := []struct {
+		name, seq string
+	}{
+		{"a.tar.gz", "1"},
+		{"a.gz", "4"},
+		{"a.z", "3"},
+		{"a.c", "0"},
+	}
+	for _, x := range tests {
+		if e := ls.MatchEntry(x.name, testDirEntry{name: x.name}); e.Seq != x.seq {
+			t.Errorf("MatchEntry(%q) = %q; want: %q", x.name, e.Seq, x.seq)
+		}
+	}
+	want := []ColorExtension{{Ext: "z", Seq: "3"}, {Ext: ".gz", Seq: "4"}, {Ext: ".tar.gz", Seq: "1"}}
+	if !reflect.DeepEqual(ls.Exts, want) {
+		t.Errorf("Exts = %+v; want: %+v", ls.Exts, want)
+	}
+	ls.Finalize() // idempotent
+	if !reflect.DeepEqual(ls.Exts, want) {
+		t.Errorf("Exts = %+v after second Finalize; want: %+v", ls.Exts, want)
+	}
+
+	// Set on an unfinalized palette must keep Exts sorted.
+	ls = &LSColors{Exts: []ColorExtension{{Ext: ".tar.gz", Seq: "1"}, {Ext: ".gz", Seq: "2"}}}
+	if err := ls.Set("*.bz2", "3"); err != nil {
+		t.Fatal(err)
+	}
+	if s := ls.String(); s != "*.gz=2:*.bz2=3:*.tar.gz=1" {
+		t.Errorf("String() = %q; want: %q", s, "*.gz=2:*.bz2=3:*.tar.gz=1")
+	}
+}
+
 func TestMatchExt(t *testing.T) {
 	// Order random to make sure we pick the right one
 	colors := []string{
@@ -124,39 +734,1533 @@ func TestMatchExt(t *testing.T) {
 	}
 }
 
-func BenchmarkMatchExt(b *testing.B) {
-	const name = "foo.README"
-	// const name = "f.c"
-	// const name = "old_CONTRIBUTORS.txt"
-	ls := benchLS
-	for i := 0; i < b.N; i++ {
-		if ls.matchExt(name) == nil {
-			b.Fatal("failed to find:", name)
-		}
+// testDirEntry is a fs.DirEntry with a controllable mode. Unlike the entries
+// returned by os.ReadDir its Type method includes the permission bits.
+type testDirEntry struct {
+	name   string
+	mode   fs.FileMode
+	broken bool // Stat returns an error
+}
+
+func (d testDirEntry) Name() string               { return d.name }
+func (d testDirEntry) IsDir() bool                { return d.mode.IsDir() }
+func (d testDirEntry) Type() fs.FileMode          { return d.mode }
+func (d testDirEntry) Info() (fs.FileInfo, error) { return nil, errors.New("not implemented") }
+
+func (d testDirEntry) Stat() (fs.FileInfo, error) {
+	if d.broken {
+		return nil, fs.ErrNotExist
 	}
+	return nil, nil
 }
 
-func BenchmarkNewLSColors(b *testing.B) {
-	clrs, ok := os.LookupEnv("LS_COLORS")
-	if !ok {
-		b.Fatal("ls_colors: LS_COLORS not set")
+func TestMatchEntryKey(t *testing.T) {
+	ls, err := ParseLSColors("di=01;34:ln=01;36:or=31:ex=01;32:fi=0:pi=33:bd=34:cd=35:*.gz=1:*.tar.gz=2")
+	if err != nil {
+		t.Fatal(err)
 	}
-	b.SetBytes(int64(len(clrs)))
-	for i := 0; i < b.N; i++ {
-		ls, err := ParseLSColors(clrs)
-		if err != nil {
-			b.Fatal(err)
+	tests := []struct {
+		entry testDirEntry
+		key   string
+		seq   string
+	}{
+		{testDirEntry{name: "dir", mode: fs.ModeDir | 0755}, "di", "01;34"},
+		{testDirEntry{name: "file", mode: 0644}, "fi", "0"},
+		{testDirEntry{name: "exe", mode: 0755}, "ex", "01;32"},
+		{testDirEntry{name: "link", mode: fs.ModeSymlink}, "ln", "01;36"},
+		{testDirEntry{name: "broken", mode: fs.ModeSymlink, broken: true}, "or", "31"},
+		{testDirEntry{name: "fifo", mode: fs.ModeNamedPipe}, "pi", "33"},
+		{testDirEntry{name: "disk", mode: fs.ModeDevice}, "bd", "34"},
+		{testDirEntry{name: "a.gz", mode: 0644}, "*.gz", "1"},
+		{testDirEntry{name: "a.tar.gz", mode: 0644}, "*.tar.gz", "2"},
+		{testDirEntry{name: "other", mode: fs.ModeIrregular}, "or", "31"},
+	}
+	for _, x := range tests {
+		ext, key := ls.MatchEntryKey(x.entry.name, x.entry)
+		if key != x.key || ext.Seq != x.seq {
+			t.Errorf("MatchEntryKey(%q) = %q, %q; want: %q, %q",
+				x.entry.name, ext.Seq, key, x.seq, x.key)
+		}
+		if e := ls.MatchEntry(x.entry.name, x.entry); e != ext {
+			t.Errorf("MatchEntry(%q) = %+v; want: %+v", x.entry.name, e, ext)
 		}
-		_ = ls
+	}
+
+	ext, key := new(LSColors).MatchEntryKey("file", testDirEntry{name: "file"})
+	if ext != &NoColor || key != "" {
+		t.Errorf("MatchEntryKey(empty) = %+v, %q; want: %+v, %q", ext, key, &NoColor, "")
 	}
 }
 
-func BenchmarkLSColorsString(b *testing.B) {
-	for i := 0; i < b.N; i++ {
+func TestMatchEntryCopy(t *testing.T) {
+	ls, err := ParseLSColors("*.go=32:*.tar=31")
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := testDirEntry{name: "main.go"}
+	ext := ls.MatchEntry("main.go", d)
+	cp := ls.MatchEntryCopy("main.go", d)
+	if *ext != cp {
+		t.Fatalf("MatchEntryCopy = %+v; want: %+v", cp, *ext)
+	}
+
+	// MatchEntry aliases c.Exts so modifying the palette changes it.
+	if err := ls.Set("*.go", "35"); err != nil {
+		t.Fatal(err)
+	}
+	if ext.Seq != "35" {
+		t.Errorf("MatchEntry: Seq = %q; want: %q", ext.Seq, "35")
+	}
+	if cp.Seq != "32" {
+		t.Errorf("MatchEntryCopy: Seq = %q; want: %q", cp.Seq, "32")
+	}
+}
+
+func TestMatchExtUnicode(t *testing.T) {
+	ls, err := ParseLSColors("*.café=32:*\xa9=31")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name, want string
+	}{
+		{"menu.café", "32"},
+		{"menu.café", ""}, // NFD
+		{"\xc2\xa9", ""},   // "©" ends with the byte 0xa9
+		{"a\xa9", "31"},
+	}
+	for _, x := range tests {
+		if e := ls.MatchEntry(x.name, testDirEntry{name: x.name}); e.Seq != x.want {
+			t.Errorf("MatchEntry(%q) = %q; want: %q", x.name, e.Seq, x.want)
+		}
+	}
+
+	// Simple stand-in for norm.NFC.String
+	ls.Normalize = func(s string) string {
+		return strings.ReplaceAll(s, "é", "é")
+	}
+	if e := ls.MatchEntry("menu.café", testDirEntry{name: "menu.café"}); e.Seq != "32" {
+		t.Errorf("Normalize: MatchEntry(%q) = %q; want: %q", "menu.café", e.Seq, "32")
+	}
+}
+
+func TestMatchDot(t *testing.T) {
+	ls, err := ParseLSColors("di=01;34:*.=31")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ls.HiddenColor = ColorExtension{Seq: "02"}
+	for _, name := range []string{".", "..", "/tmp/..", "../."} {
+		if e := ls.MatchName(name, fs.ModeDir|0755); e != &ls.DI {
+			t.Errorf("MatchName(%q) = %+v; want: %+v", name, e, ls.DI)
+		}
+	}
+	ls.DotColor = ColorExtension{Seq: "02;34"}
+	for _, name := range []string{".", ".."} {
+		if e := ls.MatchName(name, fs.ModeDir|0755); e != &ls.DotColor {
+			t.Errorf("DotColor: MatchName(%q) = %+v; want: %+v", name, e, ls.DotColor)
+		}
+		d := testDirEntry{name: name, mode: fs.ModeDir}
+		if _, key := ls.MatchEntryKey(name, d); key != "dot" {
+			t.Errorf("DotColor: MatchEntryKey(%q) = %q; want: %q", name, key, "dot")
+		}
+	}
+	// Only directories are special.
+	if e := ls.MatchName("..", 0644); e.Seq != "31" {
+		t.Errorf("MatchName(%q, file) = %q; want: %q", "..", e.Seq, "31")
+	}
+	if e := ls.MatchName("...", fs.ModeDir); e != &ls.HiddenColor {
+		t.Errorf("MatchName(%q) = %+v; want: %+v", "...", e, ls.HiddenColor)
+	}
+}
+
+func TestMatchExecutableDir(t *testing.T) {
+	modes := []fs.FileMode{
+		fs.ModeDir | 0755,
+		fs.ModeDir | 0111,
+		fs.ModeDir | 0700,
+		fs.ModeDir | fs.ModeSticky | 0777,
+		fs.ModeDir | fs.ModeSetgid | 0750,
+	}
+	tests := []struct {
+		colors, key string
+	}{
+		{"di=01;34:ex=01;32:no=02", "di"},
+		{"ex=01;32:no=02", "no"},
+		{"ex=01;32", ""},
+	}
+	for _, x := range tests {
+		ls, err := ParseLSColors(x.colors)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, mode := range modes {
+			if _, key := ls.MatchEntryKey("dir", testDirEntry{name: "dir", mode: mode}); key != x.key {
+				t.Errorf("%s: MatchEntryKey(%s) = %q; want: %q", x.colors, mode, key, x.key)
+			}
+		}
+	}
+}
+
+func TestMatchEntries(t *testing.T) {
+	dir := makeTestTree(t, "a.go", "b.tar", "c/", "d")
+	if err := os.Symlink(filepath.Join(dir, "a.go"), filepath.Join(dir, "link")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(dir, "missing"), filepath.Join(dir, "broken")); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ls, err := ParseLSColors("di=01;34:ln=01;36:or=31:*.go=32:*.tar=01;31:no=02")
+	if err != nil {
+		t.Fatal(err)
+	}
+	exts := ls.MatchEntries(dir, entries)
+	if len(exts) != len(entries) {
+		t.Fatalf("len(MatchEntries) = %d; want: %d", len(exts), len(entries))
+	}
+	for i, d := range entries {
+		if want := ls.MatchEntry(filepath.Join(dir, d.Name()), d); exts[i] != want {
+			t.Errorf("%s: MatchEntries = %+v; want: %+v", d.Name(), exts[i], want)
+		}
+	}
+
+	// Paths are joined like filepath.Join.
+	var paths []string
+	ls.ExtraMatch = func(path string, d fs.DirEntry) *ColorExtension {
+		paths = append(paths, path)
+		return nil
+	}
+	entries = []fs.DirEntry{testDirEntry{name: "a.go"}}
+	for _, dir := range []string{"", ".", "dir", "dir/"} {
+		ls.MatchEntries(dir, entries)
+	}
+	want := []string{"a.go", "a.go", filepath.Join("dir", "a.go"), filepath.Join("dir", "a.go")}
+	if !reflect.DeepEqual(paths, want) {
+		t.Errorf("MatchEntries paths = %q; want: %q", paths, want)
+	}
+}
+
+func TestNormalColor(t *testing.T) {
+	tests := []struct {
+		colors string
+		key    string
+	}{
+		{"no=02:fi=00", "no"},
+		{"fi=00:di=01;34", "fi"},
+		{"di=01;34", ""},
+	}
+	for _, x := range tests {
+		ls, err := ParseLSColors(x.colors)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := &NoColor
+		if x.key != "" {
+			want = ls.indicator(x.key)
+		}
+		if e := ls.NormalColor(); e != want {
+			t.Errorf("%s: NormalColor() = %+v; want: %+v", x.colors, e, want)
+		}
+	}
+	if e := new(LSColors).NormalColor(); e != &NoColor {
+		t.Errorf("empty: NormalColor() = %+v; want: NoColor", e)
+	}
+	ls := &LSColors{NO: ColorExtension{Ext: "no", Seq: "02"}, Disabled: RuleNO}
+	if e := ls.NormalColor(); e != &NoColor {
+		t.Errorf("disabled: NormalColor() = %+v; want: NoColor", e)
+	}
+}
+
+func TestMatchNames(t *testing.T) {
+	ls, err := ParseLSColors("*.md=33:*README.md=01;33:*Dockerfile=34:*file=31:*.tar=01;31")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]*ColorExtension{
+		"README.md":  {Ext: "README.md", Seq: "01;33"},
+		"Dockerfile": {Ext: "Dockerfile", Seq: "34"},
+		"file":       {Ext: "file", Seq: "31"},
+	}
+	if !reflect.DeepEqual(ls.Names, want) {
+		t.Errorf("Names = %+v; want: %+v", ls.Names, want)
+	}
+	ls.Names["backup.tar"] = &ColorExtension{Ext: "backup.tar", Seq: "02"}
+	tests := []struct {
+		name, seq, key string
+	}{
+		{"README.md", "01;33", "*README.md"},
+		{"Dockerfile", "34", "*Dockerfile"},
+		{"Makefile", "31", "*file"}, // suffix rules still apply
+		{"notes.md", "33", "*.md"},
+		{"backup.tar", "02", "*backup.tar"},
+		{"a.tar", "01;31", "*.tar"},
+	}
+	for _, x := range tests {
+		e, key := ls.MatchEntryKey(x.name, testDirEntry{name: x.name})
+		if e.Seq != x.seq || key != x.key {
+			t.Errorf("MatchEntryKey(%q) = %q, %q; want: %q, %q", x.name, e.Seq, key, x.seq, x.key)
+		}
+	}
+	// Matching points into c and does not allocate
+	d := testDirEntry{name: "Dockerfile"}
+	if e := ls.MatchEntry("Dockerfile", d); e != ls.Names["Dockerfile"] {
+		t.Errorf("MatchEntry(Dockerfile) = %p; want a pointer into Names", e)
+	}
+	var backup fs.DirEntry = testDirEntry{name: "backup.tar"}
+	if n := testing.AllocsPerRun(10, func() { ls.MatchEntry("backup.tar", backup) }); n != 0 {
+		t.Errorf("MatchEntry(backup.tar) allocated %.0f times", n)
+	}
+
+	if err := ls.Set("*Dockerfile", "35"); err != nil {
+		t.Fatal(err)
+	}
+	ls.Disable("*README.md")
+	if e := ls.Names["Dockerfile"]; e == nil || e.Seq != "35" || ls.Names["README.md"] != nil {
+		t.Errorf("Names not updated by Set and Disable: %+v", ls.Names)
+	}
+	if e := ls.MatchEntry("README.md", testDirEntry{name: "README.md"}); e.Seq != "33" {
+		t.Errorf("MatchEntry(README.md) = %q; want: %q", e.Seq, "33")
+	}
+}
+
+func TestMaxSuffixDepth(t *testing.T) {
+	ls, err := ParseLSColors("*.gz=1:*.tar.gz=2:*.c.tar.gz=3:*README=4:*.[0-9]*=5")
+	if err != nil {
+		t.Fatal(err)
+	}
+	deep := strings.Repeat("a.", 1000) + "tar.gz"
+	tests := []struct {
+		depth int
+		name  string
+		want  string
+	}{
+		{0, "a.b.c.tar.gz", "3"},
+		{0, deep, "2"},
+		{1, "a.b.c.tar.gz", "1"},
+		{2, "a.b.c.tar.gz", "2"},
+		{2, deep, "2"},
+		{3, "a.b.c.tar.gz", "3"},
+		{1, "README", "4"},     // no dots: matched in full
+		{1, "old.README", "4"}, // ".README" still ends with "README"
+		{1, "a.b.1.gz", "1"},
+		{1, "lib.so.1", "5"}, // ".1" matches "*.[0-9]*"
+		{0, "lib.1.so", "5"},
+		{1, "lib.1.so", ""},       // ".so" does not
+		{-1, "a.b.c.tar.gz", "3"}, // negative depths are ignored
+	}
+	for _, x := range tests {
+		ls.MaxSuffixDepth = x.depth
+		e := ls.MatchEntry(x.name, testDirEntry{name: x.name})
+		if e.Seq != x.want {
+			t.Errorf("MaxSuffixDepth=%d: MatchEntry(%.32q) = %q; want: %q", x.depth, x.name, e.Seq, x.want)
+		}
+	}
+}
+
+func TestExtraMatch(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a.go", "b.go", "c.txt"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Color files listed in a project specific "generated" attribute
+	generated := map[string]bool{filepath.Join(dir, "b.go"): true}
+	gen := ColorExtension{Ext: "gen", Seq: "02"}
+	ls, err := ParseLSColors("di=01;34:*.go=32")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var calls int
+	ls.ExtraMatch = func(path string, d fs.DirEntry) *ColorExtension {
+		calls++
+		if d.Name() != filepath.Base(path) {
+			t.Errorf("ExtraMatch(%q, %q): name does not match path", path, d.Name())
+		}
+		if generated[path] {
+			return &gen
+		}
+		return nil
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"32", "02", ""}
+	for i, d := range entries {
+		path := filepath.Join(dir, d.Name())
+		if e := ls.MatchEntry(path, d); e.Seq != want[i] {
+			t.Errorf("MatchEntry(%q) = %q; want: %q", d.Name(), e.Seq, want[i])
+		}
+		fi, err := d.Info()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if e := ls.MatchInfo(path, fi); e.Seq != want[i] {
+			t.Errorf("MatchInfo(%q) = %q; want: %q", d.Name(), e.Seq, want[i])
+		}
+	}
+	for i, e := range ls.MatchEntries(dir, entries) {
+		if e.Seq != want[i] {
+			t.Errorf("MatchEntries[%d] = %q; want: %q", i, e.Seq, want[i])
+		}
+	}
+	if _, key := ls.MatchEntryKey(filepath.Join(dir, "b.go"), entries[1]); key != "extra" {
+		t.Errorf("MatchEntryKey = %q; want: %q", key, "extra")
+	}
+	if calls != 3*len(entries)+1 {
+		t.Errorf("ExtraMatch called %d times; want: %d", calls, 3*len(entries)+1)
+	}
+}
+
+func TestSetAttribute(t *testing.T) {
+	ls, err := ParseLSColors("di=01;34:*.go=32:*.md=04:.git*=33:*.c=00;36:*.h=00")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		key  string
+		attr int
+		on   bool
+		want string
+	}{
+		{"*.c", 1, true, "00;01;36"},
+		{"*.c", 1, false, "00;36"},
+		{"*.h", 4, true, "00;04"},
+		{"*.go", 1, true, "01;32"},
+		{"*.go", 1, true, "01;32"}, // already set
+		{"*.go", 4, true, "04;01;32"},
+		{"*.go", 1, false, "04;32"},
+		{"*.go", 4, false, "32"},
+		{"*.go", 1, false, "32"}, // not set
+		{"*.md", 4, false, "0"},
+		{"di", 1, false, "34"},
+		{".git*", 5, true, "05;33"},
+	}
+	for _, x := range tests {
+		if err := ls.SetAttribute(x.key, x.attr, x.on); err != nil {
+			t.Fatal(err)
+		}
+		if e := ls.rule(x.key); e.Seq != x.want {
+			t.Errorf("SetAttribute(%q, %d, %t) = %q; want: %q", x.key, x.attr, x.on, e.Seq, x.want)
+		}
+	}
+	if e := ls.MatchEntry("main.go", testDirEntry{name: "main.go"}); e.Seq != "32" {
+		t.Errorf("MatchEntry(main.go) = %q; want: %q", e.Seq, "32")
+	}
+
+	for _, x := range []struct {
+		key  string
+		attr int
+	}{
+		{"*.cc", 1}, // no rule
+		{"ln", 1},   // indicator not set
+		{"*.go", 0}, // reset is not an attribute
+		{"*.go", 31},
+	} {
+		if err := ls.SetAttribute(x.key, x.attr, true); err == nil {
+			t.Errorf("SetAttribute(%q, %d): expected error", x.key, x.attr)
+		}
+	}
+}
+
+func TestShadowedRules(t *testing.T) {
+	// A longer extension does not shadow a shorter one
+	ls, err := ParseLSColors("*.gz=1:*.tar.gz=2:*.min.*=3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rules := ls.ShadowedRules(); len(rules) != 0 {
+		t.Errorf("ShadowedRules() = %+v; want none", rules)
+	}
+
+	ls, err = ParseLSColors("*.gz=1:*.tar.gz=2:*.gz=3:*.min.*=4:*.min.*=5:*a/b=6")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []ColorExtension{
+		{Ext: "*.min.*", Seq: "4"},
+		{Ext: "*.gz", Seq: "1"},
+		{Ext: "*a/b", Seq: "6"},
+	}
+	if rules := ls.ShadowedRules(); !reflect.DeepEqual(rules, want) {
+		t.Errorf("ShadowedRules() = %+v; want: %+v", rules, want)
+	}
+
+	ls.MaxSuffixDepth = 1
+	want = append(want, ColorExtension{Ext: "*.tar.gz", Seq: "2"})
+	if rules := ls.ShadowedRules(); !reflect.DeepEqual(rules, want) {
+		t.Errorf("ShadowedRules() = %+v; want: %+v", rules, want)
+	}
+
+	// Rules built by hand
+	ls = &LSColors{
+		Exts:  []ColorExtension{{Ext: ".c", Seq: "1"}, {Ext: ".c", Seq: "2"}},
+		Globs: []ColorExtension{{Ext: "*.min.*", Seq: "3"}, {Ext: "*.min.*", Seq: "4"}},
+	}
+	want = []ColorExtension{{Ext: "*.c", Seq: "1"}, {Ext: "*.min.*", Seq: "4"}}
+	if rules := ls.ShadowedRules(); !reflect.DeepEqual(rules, want) {
+		t.Errorf("ShadowedRules() = %+v; want: %+v", rules, want)
+	}
+
+	// Replacing rules does not shadow them
+	ls.AddExtensions([]ColorExtension{{Ext: "*.c", Seq: "5"}})
+	if err := ls.Set("*.c", "6"); err != nil {
+		t.Fatal(err)
+	}
+	if rules := ls.ShadowedRules(); !reflect.DeepEqual(rules, want) {
+		t.Errorf("ShadowedRules() = %+v; want: %+v", rules, want)
+	}
+}
+
+func TestMatchExecutableExtension(t *testing.T) {
+	ls, err := ParseLSColors("fi=0:ex=01;32:*.sh=33:*.jpg=35:*Makefile=36:*.[0-9]*=37")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		entry     testDirEntry
+		gnu, exts string // keys by default and with ExtensionsBeatExecutable
+	}{
+		// Network mounts often report every file as executable
+		{testDirEntry{name: "photo.jpg", mode: 0777}, "ex", "*.jpg"},
+		{testDirEntry{name: "Makefile", mode: 0777}, "ex", "*Makefile"},
+		{testDirEntry{name: "ls.1", mode: 0777}, "ex", "*.[0-9]*"},
+
+		{testDirEntry{name: "build.sh", mode: 0755}, "ex", "*.sh"},
+		{testDirEntry{name: "build.sh", mode: 0644}, "*.sh", "*.sh"},
+		{testDirEntry{name: "build", mode: 0755}, "ex", "ex"},
+		{testDirEntry{name: "build", mode: 0644}, "fi", "fi"},
+		{testDirEntry{name: "tool.bin", mode: 0755}, "ex", "ex"},
+	}
+	for _, x := range tests {
+		ls.ExtensionsBeatExecutable = false
+		if _, key := ls.MatchEntryKey(x.entry.name, x.entry); key != x.gnu {
+			t.Errorf("MatchEntryKey(%q, %s) = %q; want: %q", x.entry.name, x.entry.mode, key, x.gnu)
+		}
+		ls.ExtensionsBeatExecutable = true
+		if _, key := ls.MatchEntryKey(x.entry.name, x.entry); key != x.exts {
+			t.Errorf("ExtensionsBeatExecutable: MatchEntryKey(%q, %s) = %q; want: %q",
+				x.entry.name, x.entry.mode, key, x.exts)
+		}
+	}
+}
+
+func TestMatchBrokenLink(t *testing.T) {
+	dir := t.TempDir()
+	broken := filepath.Join(dir, "broken")
+	if err := os.Symlink(filepath.Join(dir, "missing"), broken); err != nil {
+		t.Fatal(err)
+	}
+	fi, err := os.Lstat(broken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		colors, want string
+	}{
+		{"ln=01;36:or=31:mi=05;31", "or"},
+		{"ln=01;36:mi=05;31", "ln"},
+		{"mi=05;31", ""},
+	}
+	for _, x := range tests {
+		ls, err := ParseLSColors(x.colors)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := &NoColor
+		if x.want != "" {
+			want = ls.indicator(x.want)
+		}
+		if _, key := ls.MatchEntryKey(broken, entries[0]); key != x.want {
+			t.Errorf("%s: MatchEntryKey = %q; want: %q", x.colors, key, x.want)
+		}
+		if e := ls.MatchEntry(broken, entries[0]); e != want {
+			t.Errorf("%s: MatchEntry = %+v; want: %+v", x.colors, e, want)
+		}
+		if e := ls.MatchInfo(broken, fi); e != want {
+			t.Errorf("%s: MatchInfo = %+v; want: %+v", x.colors, e, want)
+		}
+	}
+}
+
+func TestMatchFollowLinks(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, "dir"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "file"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"dir", "file", "missing"} {
+		if err := os.Symlink(filepath.Join(dir, name), filepath.Join(dir, name+"-link")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ls, err := ParseLSColors("di=01;34:ln=01;36:or=31")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name         string
+		want, follow string
+	}{
+		{"dir-link", "ln", "di"},
+		{"file-link", "ln", "ln"},
+		{"missing-link", "or", "or"},
+	}
+	for _, x := range tests {
+		path := filepath.Join(dir, x.name)
+		fi, err := os.Lstat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		d := fs.FileInfoToDirEntry(fi)
+		ls.FollowLinks = false
+		if _, key := ls.MatchEntryKey(path, d); key != x.want {
+			t.Errorf("MatchEntryKey(%q) = %q; want: %q", x.name, key, x.want)
+		}
+		ls.FollowLinks = true
+		if _, key := ls.MatchEntryKey(path, d); key != x.follow {
+			t.Errorf("FollowLinks: MatchEntryKey(%q) = %q; want: %q", x.name, key, x.follow)
+		}
+		if e := ls.MatchInfo(path, fi); e != ls.indicator(x.follow) {
+			t.Errorf("FollowLinks: MatchInfo(%q) = %+v; want: %+v", x.name, e, ls.indicator(x.follow))
+		}
+	}
+}
+
+func TestMatchInfoExecutableExtension(t *testing.T) {
+	dir := t.TempDir()
+	script := filepath.Join(dir, "build.sh")
+	if err := os.WriteFile(script, nil, 0755); err != nil {
+		t.Fatal(err)
+	}
+	fi, err := os.Lstat(script)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Mode()&0111 == 0 {
+		t.Skip("file system does not support executable bits")
+	}
+	ls, err := ParseLSColors("ex=01;32:*.sh=33")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e := ls.MatchInfo(script, fi); e != &ls.EX {
+		t.Errorf("MatchInfo(%q) = %+v; want: %+v", script, e, ls.EX)
+	}
+	ls.ExtensionsBeatExecutable = true
+	if e := ls.MatchInfo(script, fi); e.Seq != "33" {
+		t.Errorf("ExtensionsBeatExecutable: MatchInfo(%q) = %+v; want: %q", script, e, "33")
+	}
+}
+
+func TestMatchSpecialFallback(t *testing.T) {
+	tests := []struct {
+		colors string
+		mode   fs.FileMode
+		key    string
+	}{
+		{"no=02:pi=33", fs.ModeNamedPipe, "pi"},
+		{"no=02:fi=0", fs.ModeNamedPipe, "no"},
+		{"no=02:or=31", fs.ModeNamedPipe, "no"},
+		{"fi=0", fs.ModeNamedPipe, ""},
+		{"no=02:so=35", fs.ModeSocket, "so"},
+		{"no=02:pi=33", fs.ModeSocket, "no"},
+		{"no=02:bd=33", fs.ModeDevice, "bd"},
+		{"no=02:cd=34", fs.ModeDevice, "no"},
+		{"no=02:bd=33:cd=34", fs.ModeDevice | fs.ModeCharDevice, "cd"},
+		{"no=02:bd=33", fs.ModeDevice | fs.ModeCharDevice, "no"},
+	}
+	for _, x := range tests {
+		ls, err := ParseLSColors(x.colors)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ext, key := ls.MatchEntryKey("x", testDirEntry{name: "x", mode: x.mode})
+		if key != x.key {
+			t.Errorf("%s: MatchEntryKey(%s) = %q; want: %q", x.colors, x.mode, key, x.key)
+		}
+		if x.key == "" && ext != &NoColor {
+			t.Errorf("%s: MatchEntryKey(%s) = %+v; want: NoColor", x.colors, x.mode, ext)
+		}
+	}
+}
+
+func TestSocketsAsPipes(t *testing.T) {
+	tests := []struct {
+		colors string
+		pipes  bool
+		key    string
+	}{
+		{"no=02:pi=33:so=35", false, "so"},
+		{"no=02:pi=33:so=35", true, "so"},
+		{"no=02:pi=33", false, "no"},
+		{"no=02:pi=33", true, "pi"},
+		{"no=02", true, "no"},
+		{"fi=0", false, ""},
+		{"fi=0", true, ""},
+	}
+	d := testDirEntry{name: "x", mode: fs.ModeSocket}
+	for _, x := range tests {
+		ls, err := ParseLSColors(x.colors)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ls.SocketsAsPipes = x.pipes
+		ext, key := ls.MatchEntryKey("x", d)
+		if key != x.key {
+			t.Errorf("%s (SocketsAsPipes=%t): MatchEntryKey = %q; want: %q", x.colors, x.pipes, key, x.key)
+		}
+		if x.key == "" && ext != &NoColor {
+			t.Errorf("%s (SocketsAsPipes=%t): MatchEntryKey = %+v; want: NoColor", x.colors, x.pipes, ext)
+		}
+	}
+
+	// Disabling PI also disables the fallback
+	ls, err := ParseLSColors("no=02:pi=33")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ls.SocketsAsPipes = true
+	ls.Disabled = RulePI
+	if _, key := ls.MatchEntryKey("x", d); key != "no" {
+		t.Errorf("Disabled PI: MatchEntryKey = %q; want: %q", key, "no")
+	}
+}
+
+// Exts is allocated once with room for every entry from the first extension
+// on, so appending the extensions never grows it.
+func TestMatchGlob(t *testing.T) {
+	ls, err := ParseLSColors("fi=0:.git*=1:*.min.*=2:*.min.js=3:*.js=4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name, key, seq string
+	}{
+		{".gitignore", ".git*", "1"},
+		{".git", ".git*", "1"},
+		{"a.min.css", "*.min.*", "2"},
+		{"a.min.js", "*.min.js", "3"}, // suffix rules take precedence
+		{"a.js", "*.js", "4"},
+		{"x.gitignore", "fi", "0"},
+		{"a.min", "fi", "0"},
+		{"amin.css", "fi", "0"},
+	}
+	for _, x := range tests {
+		ext, key := ls.MatchEntryKey(x.name, testDirEntry{name: x.name})
+		if key != x.key || ext.Seq != x.seq {
+			t.Errorf("MatchEntryKey(%q) = %q, %q; want: %q, %q",
+				x.name, ext.Seq, key, x.seq, x.key)
+		}
+	}
+
+	// Directories are not matched by globs
+	if e := ls.MatchEntry(".github", testDirEntry{name: ".github", mode: fs.ModeDir}); e != &NoColor {
+		t.Errorf("MatchEntry(.github) = %+v; want: NoColor", e)
+	}
+
+	if _, err := ParseLSColors("*[.x*=1"); err == nil {
+		t.Error("expected error for malformed glob")
+	}
+
+	ls.Disable(".git*")
+	if e := ls.MatchEntry(".gitignore", testDirEntry{name: ".gitignore"}); e != &ls.FI {
+		t.Errorf("MatchEntry(.gitignore) = %+v; want: %+v", e, ls.FI)
+	}
+}
+
+func TestHiddenColor(t *testing.T) {
+	ls, err := ParseLSColors("di=01;34:fi=0:ex=01;32:*.go=32")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		entry testDirEntry
+		key   string
+	}{
+		{testDirEntry{name: ".bashrc"}, "hidden"},
+		{testDirEntry{name: ".local", mode: fs.ModeDir}, "hidden"},
+		{testDirEntry{name: ".script", mode: 0755}, "hidden"},
+		{testDirEntry{name: ".x.go"}, "*.go"}, // extensions are more specific
+		{testDirEntry{name: ".", mode: fs.ModeDir}, "di"},
+		{testDirEntry{name: "..", mode: fs.ModeDir}, "di"},
+		{testDirEntry{name: "file"}, "fi"},
+	}
+
+	// Opt-in: hidden files are colored normally by default.
+	for _, x := range tests {
+		if _, key := ls.MatchEntryKey(x.entry.name, x.entry); key == "hidden" {
+			t.Errorf("MatchEntryKey(%q) = %q without HiddenColor", x.entry.name, key)
+		}
+	}
+
+	ls.HiddenColor = ColorExtension{Seq: "02"}
+	for _, x := range tests {
+		ext, key := ls.MatchEntryKey(x.entry.name, x.entry)
+		if key != x.key {
+			t.Errorf("MatchEntryKey(%q) = %q; want: %q", x.entry.name, key, x.key)
+		}
+		if key == "hidden" && ext != &ls.HiddenColor {
+			t.Errorf("MatchEntryKey(%q) = %+v; want: %+v", x.entry.name, ext, ls.HiddenColor)
+		}
+	}
+}
+
+func TestColorPath(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "file.txt")
+	if err := os.WriteFile(file, []byte("a"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	link := filepath.Join(dir, "link")
+	if err := os.Symlink(file, link); err != nil {
+		t.Fatal(err)
+	}
+	broken := filepath.Join(dir, "broken")
+	if err := os.Symlink(filepath.Join(dir, "nope"), broken); err != nil {
+		t.Fatal(err)
+	}
+	missing := filepath.Join(dir, "missing")
+
+	ls, err := ParseLSColors("di=01;34:ln=01;36:or=31:mi=05;31:*.txt=32")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		path, want string
+	}{
+		{dir, "\x1b[01;34m" + filepath.Base(dir) + "\x1b[0m"},
+		{file, "\x1b[32mfile.txt\x1b[0m"},
+		{link, "\x1b[01;36mlink\x1b[0m"},
+		{broken, "\x1b[31mbroken\x1b[0m"},
+	}
+	for _, x := range tests {
+		got, err := ls.ColorPath(x.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != x.want {
+			t.Errorf("ColorPath(%q) = %q; want: %q", x.path, got, x.want)
+		}
+	}
+
+	if _, err := ls.ColorPath(missing); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("ColorPath(%q) error = %v; want: %v", missing, err, fs.ErrNotExist)
+	}
+	ls.ColorMissing = true
+	got, err := ls.ColorPath(missing)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "\x1b[05;31mmissing\x1b[0m"; got != want {
+		t.Errorf("ColorPath(%q) = %q; want: %q", missing, got, want)
+	}
+}
+
+func TestFormatReset(t *testing.T) {
+	// A customized "rs" must not change the reset emitted after each name.
+	for _, colors := range []string{"*.txt=04", "rs=04:*.txt=04", "rs=0:no=04:*.txt=3;04"} {
+		ls, err := ParseLSColors(colors)
+		if err != nil {
+			t.Fatal(err)
+		}
+		e := ls.MatchEntry("a.txt", testDirEntry{name: "a.txt"})
+		s := e.Format("a.txt") + "after"
+		if !strings.HasSuffix(s, "\x1b[0mafter") {
+			t.Errorf("%s: underline bleeds into following text: %q", colors, s)
+		}
+		if b := e.AppendFormat(nil, "a.txt"); string(b) != e.Format("a.txt") {
+			t.Errorf("%s: AppendFormat = %q; want: %q", colors, b, e.Format("a.txt"))
+		}
+	}
+}
+
+func TestAppendPath(t *testing.T) {
+	ls, err := ParseLSColors("di=01;34:*.go=32")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"a/b/main.go", "\x1b[01;34ma/b/\x1b[0m\x1b[32mmain.go\x1b[0m"},
+		{"main.go", "\x1b[32mmain.go\x1b[0m"},
+	}
+	for _, x := range tests {
+		d := testDirEntry{name: filepath.Base(x.path)}
+		b := ls.AppendPath([]byte("> "), x.path, d)
+		if got := string(b); got != "> "+x.want {
+			t.Errorf("AppendPath(%q) = %q; want: %q", x.path, got, "> "+x.want)
+		}
+	}
+}
+
+func TestAppendPathSeparatorColor(t *testing.T) {
+	ls, err := ParseLSColors("di=01;34:*.go=32")
+	if err != nil {
+		t.Fatal(err)
+	}
+	const path = "/src/a/main.go"
+	d := testDirEntry{name: "main.go"}
+	di := func(s string) string { return "\x1b[01;34m" + s + "\x1b[0m" }
+	file := "\x1b[32mmain.go\x1b[0m"
+	tests := []struct {
+		sep  *ColorExtension
+		want string
+	}{
+		{nil, di("/src/a/") + file},
+		{&ColorExtension{}, "/" + di("src") + "/" + di("a") + "/" + file},
+		{
+			&ColorExtension{Seq: "02"},
+			"\x1b[02m/\x1b[0m" + di("src") + "\x1b[02m/\x1b[0m" + di("a") + "\x1b[02m/\x1b[0m" + file,
+		},
+	}
+	for _, x := range tests {
+		ls.SeparatorColor = x.sep
+		if got := string(ls.AppendPath(nil, path, d)); got != x.want {
+			t.Errorf("AppendPath(%q) with SeparatorColor %+v:\ngot:  %q\nwant: %q", path, x.sep, got, x.want)
+		}
+	}
+
+	// No empty DI escape is written for a path without a directory
+	ls.SeparatorColor = &ColorExtension{Seq: "02"}
+	if got := string(ls.AppendPath(nil, "main.go", d)); got != file {
+		t.Errorf("AppendPath(%q) = %q; want: %q", "main.go", got, file)
+	}
+	if c := ls.Clone(); c.SeparatorColor == ls.SeparatorColor {
+		t.Error("Clone: SeparatorColor is shared")
+	}
+}
+
+func TestFormattedLen(t *testing.T) {
+	for _, e := range []ColorExtension{{}, {Seq: "01;34"}, {Ext: ".go", Seq: "38;5;208"}} {
+		for _, s := range []string{"", "a", "file.go", "日本語"} {
+			if n, want := e.FormattedLen(s), len(e.Format(s)); n != want {
+				t.Errorf("%+v.FormattedLen(%q) = %d; want: %d", e, s, n, want)
+			}
+		}
+	}
+}
+
+func TestFormatNoColor(t *testing.T) {
+	for _, e := range []ColorExtension{NoColor, {Ext: "fi"}} {
+		if got := e.Format("file"); got != "file" {
+			t.Errorf("%+v.Format = %q; want: %q", e, got, "file")
+		}
+		if got := string(e.AppendFormat([]byte("a/"), "file")); got != "a/file" {
+			t.Errorf("%+v.AppendFormat = %q; want: %q", e, got, "a/file")
+		}
+		want := "\x1b[0mfile\x1b[0m"
+		if got := e.FormatReset("file"); got != want {
+			t.Errorf("%+v.FormatReset = %q; want: %q", e, got, want)
+		}
+		if got := string(e.AppendFormatReset(nil, "file")); got != want {
+			t.Errorf("%+v.AppendFormatReset = %q; want: %q", e, got, want)
+		}
+	}
+	e := ColorExtension{Seq: "01;34"}
+	if got, want := e.FormatReset("dir"), e.Format("dir"); got != want {
+		t.Errorf("FormatReset = %q; want: %q", got, want)
+	}
+	if got, want := string(e.AppendFormatReset(nil, "dir")), e.Format("dir"); got != want {
+		t.Errorf("AppendFormatReset = %q; want: %q", got, want)
+	}
+}
+
+func TestWriteString(t *testing.T) {
+	var sb strings.Builder
+	for _, e := range []ColorExtension{{}, {Seq: "01;34"}, {Ext: ".go", Seq: "38;5;208"}} {
+		for _, s := range []string{"", "file.go", "日本語"} {
+			sb.Reset()
+			e.WriteString(&sb, s)
+			if got, want := sb.String(), e.Format(s); got != want {
+				t.Errorf("%+v.WriteString(%q) = %q; want: %q", e, s, got, want)
+			}
+		}
+	}
+
+	ls, err := ParseLSColors("di=01;34:*.go=32")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sb.Reset()
+	var want string
+	for _, d := range []testDirEntry{{name: "dir", mode: os.ModeDir}, {name: "a.go"}, {name: "b.txt"}} {
+		ls.WriteEntry(&sb, d.name, d)
+		sb.WriteByte(' ')
+		want += ls.MatchEntry(d.name, d).Format(d.name) + " "
+	}
+	if got := sb.String(); got != want {
+		t.Errorf("WriteEntry:\ngot:  %q\nwant: %q", got, want)
+	}
+}
+
+// Formatting must not write to the palette so that it can be shared by
+// concurrent readers (see PaletteHolder). Run with -race.
+func TestFormatConcurrentRead(t *testing.T) {
+	ls, err := ParseLSColors("di=01;34:*.go=32:.git*=1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		var b []byte
+		var sb strings.Builder
+		for i := 0; i < 100; i++ {
+			_ = ls.DI.Format("dir")
+			b = ls.Exts[0].AppendFormat(b[:0], "a.go")
+			ls.Globs[0].WriteString(&sb, ".gitignore")
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		_ = ls.String()
+		_ = ls.Clone()
+		_ = ls.MatchEntryCopy("a.go", testDirEntry{name: "a.go"})
+	}
+	<-done
+}
+
+func TestFormatReverse(t *testing.T) {
+	tests := []struct {
+		seq, want string
+	}{
+		{"", "\x1b[7mfile\x1b[0m"},
+		{"01;34", "\x1b[01;34;7mfile\x1b[0m"},
+		{"38;5;208", "\x1b[38;5;208;7mfile\x1b[0m"},
+		{"00", "\x1b[00;7mfile\x1b[0m"},
+		{"00;36", "\x1b[00;36;7mfile\x1b[0m"},
+	}
+	for _, x := range tests {
+		e := ColorExtension{Seq: x.seq}
+		if got := e.FormatReverse("file"); got != x.want {
+			t.Errorf("FormatReverse(%q) = %q; want: %q", x.seq, got, x.want)
+		}
+	}
+}
+
+func TestFormatEntryWidth(t *testing.T) {
+	ls, err := ParseLSColors("di=01;34:*.go=32")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		entry testDirEntry
+		width int
+	}{
+		{testDirEntry{name: "main.go"}, 7},
+		{testDirEntry{name: "日本語.go"}, 9},
+		{testDirEntry{name: "café", mode: fs.ModeDir}, 4},
+		{testDirEntry{name: "cafe\u0301"}, 4},
+		{testDirEntry{name: ""}, 0},
+	}
+	for _, x := range tests {
+		s, n := ls.FormatEntryWidth(x.entry.name, x.entry)
+		if want := ls.MatchEntry(x.entry.name, x.entry).Format(x.entry.name); s != want {
+			t.Errorf("FormatEntryWidth(%q) = %q; want: %q", x.entry.name, s, want)
+		}
+		if n != x.width {
+			t.Errorf("FormatEntryWidth(%q) width = %d; want: %d", x.entry.name, n, x.width)
+		}
+	}
+}
+
+func TestFormatComponents(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "a", "b"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "a", "b", "c.go"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	ls, err := ParseLSColors("di=01;34:*.go=32")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sep := string(filepath.Separator)
+	di := ls.DI.Format
+	file := func(s string) string { return "\x1b[32m" + s + "\x1b[0m" }
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+
+	tests := []struct {
+		path, want string
+	}{
+		{"a", di("a")},
+		{"a/b/c.go", di("a") + sep + di("b") + sep + file("c.go")},
+		{"./a//b/", di("a") + sep + di("b")},
+		{"a/b/../b/c.go", di("a") + sep + di("b") + sep + file("c.go")},
+		{"..", di("..")},
+	}
+	for _, x := range tests {
+		got, err := ls.FormatComponents(filepath.FromSlash(x.path))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != x.want {
+			t.Errorf("FormatComponents(%q) = %q; want: %q", x.path, got, x.want)
+		}
+	}
+
+	// Absolute paths keep their leading separator uncolored.
+	abs := filepath.Join(dir, "a", "b", "c.go")
+	got, err := ls.FormatComponents(abs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if Strip(got) != abs {
+		t.Errorf("FormatComponents(%q) = %q; want: %q when stripped", abs, got, abs)
+	}
+	if !strings.HasSuffix(got, sep+di("b")+sep+file("c.go")) {
+		t.Errorf("FormatComponents(%q) = %q", abs, got)
+	}
+
+	ls.SeparatorColor = &ColorExtension{Seq: "02"}
+	got, err = ls.FormatComponents(filepath.Join("a", "b", "c.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	csep := "\x1b[02m" + sep + "\x1b[0m"
+	if want := di("a") + csep + di("b") + csep + file("c.go"); got != want {
+		t.Errorf("FormatComponents with SeparatorColor = %q; want: %q", got, want)
+	}
+	ls.SeparatorColor = nil
+
+	if _, err := ls.FormatComponents(filepath.Join("a", "missing", "c.go")); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("error = %v; want: %v", err, fs.ErrNotExist)
+	}
+}
+
+func TestFormatKeepBackground(t *testing.T) {
+	tests := []struct {
+		seq, want string
+	}{
+		{"", "file"},
+		{"01;34", "\x1b[01;34mfile\x1b[22;39m"},
+		{"0;32", "\x1b[0;32mfile\x1b[39m"},
+		{"04;02;35", "\x1b[04;02;35mfile\x1b[22;24;39m"},
+		{"38;5;208", "\x1b[38;5;208mfile\x1b[39m"},
+		{"38;2;1;2;3;07", "\x1b[38;2;1;2;3;07mfile\x1b[27;39m"},
+		{"30;43", "\x1b[30;43mfile\x1b[39;49m"},
+		{"0", "\x1b[0mfile"},
+	}
+	for _, x := range tests {
+		e := ColorExtension{Seq: x.seq}
+		if got := e.FormatKeepBackground("file"); got != x.want {
+			t.Errorf("FormatKeepBackground(%q) = %q; want: %q", x.seq, got, x.want)
+		}
+	}
+}
+
+func TestFormatClassify(t *testing.T) {
+	ls, err := ParseLSColors("di=01;34:ln=01;36:pi=33:so=35:ex=01;32:fi=0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		mode fs.FileMode
+		want string
+	}{
+		{fs.ModeDir | 0755, "\x1b[01;34mx\x1b[0m/"},
+		{0755, "\x1b[01;32mx\x1b[0m*"},
+		{fs.ModeSymlink, "\x1b[01;36mx\x1b[0m@"},
+		{fs.ModeNamedPipe, "\x1b[33mx\x1b[0m|"},
+		{fs.ModeSocket, "\x1b[35mx\x1b[0m="},
+		{0644, "\x1b[0mx\x1b[0m"},
+	}
+	for _, x := range tests {
+		got := ls.FormatClassify("x", "x", testDirEntry{name: "x", mode: x.mode})
+		if got != x.want {
+			t.Errorf("FormatClassify(%s) = %q; want: %q", x.mode, got, x.want)
+		}
+	}
+
+	// Entries read from a directory only report the type bits.
+	dir := makeTestTree(t, "file")
+	run := filepath.Join(dir, "run")
+	if err := os.WriteFile(run, nil, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(run, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if fi, err := os.Stat(run); err != nil {
+		t.Fatal(err)
+	} else if fi.Mode()&0111 == 0 {
+		t.Skip("file system does not support executable bits")
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"file": "\x1b[0mfile\x1b[0m",
+		"run":  "\x1b[01;32mrun\x1b[0m*",
+	}
+	for _, d := range entries {
+		got := ls.FormatClassify(filepath.Join(dir, d.Name()), d.Name(), d)
+		if got != want[d.Name()] {
+			t.Errorf("FormatClassify(%q) = %q; want: %q", d.Name(), got, want[d.Name()])
+		}
+	}
+}
+
+func TestDisabledRules(t *testing.T) {
+	ls, err := ParseLSColors("di=01;34:ln=01;36:no=00:*.tar=01;31:*.min.*=02")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ls.Disabled = RuleExts
+	tests := []struct {
+		entry testDirEntry
+		want  string
+	}{
+		{testDirEntry{name: "a.tar"}, "00"},
+		{testDirEntry{name: "a.min.js"}, "00"},
+		{testDirEntry{name: "dir", mode: fs.ModeDir}, "01;34"},
+		{testDirEntry{name: "link", mode: fs.ModeSymlink}, "01;36"},
+	}
+	for _, x := range tests {
+		if e := ls.MatchEntry(x.entry.name, x.entry); e.Seq != x.want {
+			t.Errorf("MatchEntry(%q) = %q; want: %q", x.entry.name, e.Seq, x.want)
+		}
+	}
+	if len(ls.Exts) != 1 || len(ls.Globs) != 1 {
+		t.Errorf("disabling rules modified the palette: %+v %+v", ls.Exts, ls.Globs)
+	}
+
+	ls.Disabled = RuleDI | RuleNO
+	if e := ls.MatchEntry("dir", testDirEntry{name: "dir", mode: fs.ModeDir}); e != &NoColor {
+		t.Errorf("MatchEntry(dir) = %+v; want: NoColor", e)
+	}
+	if e := ls.MatchEntry("a.tar", testDirEntry{name: "a.tar"}); e.Seq != "01;31" {
+		t.Errorf("MatchEntry(a.tar) = %q; want: %q", e.Seq, "01;31")
+	}
+
+	ls.Disabled = 0
+	if e := ls.MatchEntry("dir", testDirEntry{name: "dir", mode: fs.ModeDir}); e.Seq != "01;34" {
+		t.Errorf("MatchEntry(dir) = %q; want: %q", e.Seq, "01;34")
+	}
+}
+
+func TestClone(t *testing.T) {
+	ls, err := ParseLSColors("di=01;34:*.go=32:.git*=1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ls.Git = &GitColors{Modified: ColorExtension{Seq: "33"}}
+	ls.ShebangRules = map[string]ColorExtension{"python": {Seq: "35"}}
+	ls.Groups = map[string]string{".go": "code"}
+	c := ls.Clone()
+	if !reflect.DeepEqual(c, ls) {
+		t.Fatalf("Clone() = %+v; want: %+v", c, ls)
+	}
+	c.Exts[0].Seq = "1"
+	c.Globs[0].Seq = "2"
+	c.Git.Modified.Seq = "3"
+	c.ShebangRules["python"] = ColorExtension{Seq: "4"}
+	c.Groups[".go"] = "source"
+	if ls.Exts[0].Seq != "32" || ls.Globs[0].Seq != "1" || ls.Git.Modified.Seq != "33" ||
+		ls.ShebangRules["python"].Seq != "35" || ls.Groups[".go"] != "code" {
+		t.Errorf("modifying the clone modified the original: %+v", ls)
+	}
+}
+
+func TestStripAttribute(t *testing.T) {
+	ls, err := ParseLSColors("di=01;34:ln=1:ex=01;38;5;1:fi=38;2;1;1;1:*.go=1;32;1:*.c=5;31:.git*=01")
+	if err != nil {
+		t.Fatal(err)
+	}
+	orig := ls.String()
+	got := ls.StripAttribute(1).String()
+	want := "di=34:fi=38;2;1;1;1:ln=0:ex=38;5;1:*.c=5;31:*.go=32:.git*=0"
+	if got != want {
+		t.Errorf("StripAttribute(1) = %q; want: %q", got, want)
+	}
+	if got := ls.StripAttribute(5).String(); !strings.Contains(got, "*.c=31") {
+		t.Errorf("StripAttribute(5) = %q; want it to contain %q", got, "*.c=31")
+	}
+	if ls.String() != orig {
+		t.Errorf("StripAttribute modified the palette: %q", ls.String())
+	}
+}
+
+func TestStripAttributeExtraColors(t *testing.T) {
+	ls := &LSColors{
+		HiddenColor:    ColorExtension{Seq: "01;30"},
+		DotColor:       ColorExtension{Seq: "01;37"},
+		SeparatorColor: &ColorExtension{Seq: "01"},
+		TypeRules:      []TypeRule{{Type: TypeDir, ColorExtension: ColorExtension{Seq: "01;32"}}},
+		SizeRules:      []SizeRule{{Min: 1 << 20, ColorExtension: ColorExtension{Seq: "01;31"}}},
+		AgeRules:       []AgeRule{{Age: 1, ColorExtension: ColorExtension{Seq: "01;33"}}},
+		DeviceRules:    []DeviceRule{{Major: 8, Block: true, ColorExtension: ColorExtension{Seq: "01;34"}}},
+		ShebangRules:   map[string]ColorExtension{"python": {Seq: "01;35"}},
+		Git:            &GitColors{Staged: ColorExtension{Seq: "01;36"}},
+	}
+	got := ls.StripAttribute(1)
+	seqs := map[string]string{
+		"HiddenColor":    got.HiddenColor.Seq,
+		"DotColor":       got.DotColor.Seq,
+		"SeparatorColor": got.SeparatorColor.Seq,
+		"TypeRules":      got.TypeRules[0].Seq,
+		"SizeRules":      got.SizeRules[0].Seq,
+		"AgeRules":       got.AgeRules[0].Seq,
+		"DeviceRules":    got.DeviceRules[0].Seq,
+		"ShebangRules":   got.ShebangRules["python"].Seq,
+		"Git.Staged":     got.Git.Staged.Seq,
+	}
+	for name, seq := range seqs {
+		if strings.HasPrefix(seq, "01") {
+			t.Errorf("StripAttribute(1): %s = %q; want bold removed", name, seq)
+		}
+	}
+	if ls.ShebangRules["python"].Seq != "01;35" || ls.TypeRules[0].Seq != "01;32" {
+		t.Errorf("StripAttribute modified the palette: %+v", ls)
+	}
+	if b := ls.BoldToBright(); b.SizeRules[0].Seq != "91" || b.ShebangRules["python"].Seq != "95" {
+		t.Errorf("BoldToBright: SizeRules[0] = %q, ShebangRules[python] = %q; want: %q, %q",
+			b.SizeRules[0].Seq, b.ShebangRules["python"].Seq, "91", "95")
+	}
+}
+
+func TestBoldToBright(t *testing.T) {
+	ls, err := ParseLSColors("di=01;34:ln=1;36;41:ex=01;32;01:so=01:pi=33:" +
+		"bd=01;38;5;33:cd=01;41:*.tar=04;01;31:*.go=01;93")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := ls.BoldToBright()
+	const want = "di=94:ln=96;101:pi=33:so=01:bd=01;38;5;33:cd=101:ex=92:" +
+		"*.go=01;93:*.tar=04;91"
+	if s := got.String(); s != want {
+		t.Errorf("BoldToBright:\ngot:  %q\nwant: %q", s, want)
+	}
+	if ls.DI.Seq != "01;34" {
+		t.Errorf("BoldToBright modified the palette: DI = %q", ls.DI.Seq)
+	}
+}
+
+func TestReset(t *testing.T) {
+	tests := []struct {
+		colors, want string
+	}{
+		{"di=01;34", "\x1b[0m"},
+		{"rs=0", "\x1b[0m"},
+		{"rs=00", "\x1b[00m"},
+		{"rs=0;49", "\x1b[0;49m"},
+		{"ec=\\e[0;39m:rs=1", "\x1b[0;39m"},
+		{"lc=\\e[:rc=m:rs=00", "\x1b[00m"},
+		{"lc=\\033[2;:rc=m", "\x1b[2;0m"},
+		{"rc=X:rs=0", "\x1b[0X"},
+	}
+	for _, x := range tests {
+		ls, err := ParseLSColors(x.colors)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := ls.Reset(); got != x.want {
+			t.Errorf("%s: Reset() = %q; want: %q", x.colors, got, x.want)
+		}
+	}
+	if got := new(LSColors).Reset(); got != "\x1b[0m" {
+		t.Errorf("LSColors{}.Reset() = %q; want: %q", got, "\x1b[0m")
+	}
+}
+
+func TestDisable(t *testing.T) {
+	ls, err := ParseLSColors("di=01;34:ln=01;36:fi=0:*.gz=1:*.tar.gz=2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ls.Disable("di", "*.gz", "xx", "*.nope")
+	if !ls.DI.Empty() {
+		t.Errorf("DI = %+v; want empty", ls.DI)
+	}
+	if e := ls.MatchEntry("dir", testDirEntry{name: "dir", mode: fs.ModeDir}); e != &NoColor {
+		t.Errorf("MatchEntry(dir) = %+v; want: NoColor", e)
+	}
+	if e := ls.MatchEntry("a.gz", testDirEntry{name: "a.gz"}); e != &ls.FI {
+		t.Errorf("MatchEntry(a.gz) = %+v; want: %+v", e, ls.FI)
+	}
+	// Other rules are unaffected
+	if e := ls.MatchEntry("a.tar.gz", testDirEntry{name: "a.tar.gz"}); e.Seq != "2" {
+		t.Errorf("MatchEntry(a.tar.gz) = %+v; want: %q", e, "2")
+	}
+	if e := ls.MatchEntry("link", testDirEntry{name: "link", mode: fs.ModeSymlink}); e != &ls.LN {
+		t.Errorf("MatchEntry(link) = %+v; want: %+v", e, ls.LN)
+	}
+	if got, want := ls.String(), "fi=0:ln=01;36:*.tar.gz=2"; got != want {
+		t.Errorf("String() = %q; want: %q", got, want)
+	}
+}
+
+func BenchmarkFormat(b *testing.B) {
+	e := ColorExtension{Ext: ".go", Seq: "38;5;208"}
+	b.Run("Format", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			_ = e.Format("main.go")
+		}
+	})
+	b.Run("AppendFormat", func(b *testing.B) {
+		var buf []byte
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			buf = e.AppendFormat(buf[:0], "main.go")
+		}
+	})
+	b.Run("NoColor", func(b *testing.B) {
+		var buf []byte
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			buf = NoColor.AppendFormat(buf[:0], "main.go")
+		}
+	})
+	b.Run("NoColorReset", func(b *testing.B) {
+		var buf []byte
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			buf = NoColor.AppendFormatReset(buf[:0], "main.go")
+		}
+	})
+}
+
+func BenchmarkMatchExt(b *testing.B) {
+	const name = "foo.README"
+	// const name = "f.c"
+	// const name = "old_CONTRIBUTORS.txt"
+	ls := benchLS
+	for i := 0; i < b.N; i++ {
+		if ls.matchExt(name) == nil {
+			b.Fatal("failed to find:", name)
+		}
+	}
+}
+
+func BenchmarkMatchEntries(b *testing.B) {
+	dir := b.TempDir()
+	exts := []string{".go", ".tar", ".txt", ".png", ""}
+	for i := 0; i < 1000; i++ {
+		name := filepath.Join(dir, "file"+strconv.Itoa(i)+exts[i%len(exts)])
+		if err := os.WriteFile(name, nil, 0644); err != nil {
+			b.Fatal(err)
+		}
+		if i%10 == 0 {
+			if err := os.Symlink(name, name+".link"); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.Run("MatchEntries", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			benchLS.MatchEntries(dir, entries)
+		}
+	})
+	b.Run("MatchEntry", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			for _, d := range entries {
+				benchLS.MatchEntry(filepath.Join(dir, d.Name()), d)
+			}
+		}
+	})
+}
+
+func BenchmarkNewLSColors(b *testing.B) {
+	clrs, ok := os.LookupEnv("LS_COLORS")
+	if !ok {
+		b.Fatal("ls_colors: LS_COLORS not set")
+	}
+	b.SetBytes(int64(len(clrs)))
+	for i := 0; i < b.N; i++ {
+		ls, err := ParseLSColors(clrs)
+		if err != nil {
+			b.Fatal(err)
+		}
+		_ = ls
+	}
+}
+
+func BenchmarkParseLSColorsBytes(b *testing.B) {
+	data := []byte(strings.Join(hugeLSCOLOR, ":"))
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := ParseLSColorsBytes(data); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkParseLSColorsExts parses an extension heavy palette whose first
+// extension is preceded by many indicators and globs. Exts should be
+// allocated once with the exact capacity.
+func BenchmarkParseLSColorsExts(b *testing.B) {
+	var entries []string
+	for i := 0; i < 100; i++ {
+		entries = append(entries, fmt.Sprintf(".g%d*=01;3%d", i, i%8))
+	}
+	for i := 0; i < 1000; i++ {
+		entries = append(entries, fmt.Sprintf("*.e%d=38;5;%d", i, i%256))
+	}
+	clrs := strings.Join(entries, ":")
+	b.SetBytes(int64(len(clrs)))
+	b.ReportAllocs()
+	var spare int
+	for i := 0; i < b.N; i++ {
+		ls, err := ParseLSColors(clrs)
+		if err != nil {
+			b.Fatal(err)
+		}
+		spare = cap(ls.Exts) - len(ls.Exts)
+	}
+	b.ReportMetric(float64(spare), "spare-exts")
+}
+
+func BenchmarkLSColorsString(b *testing.B) {
+	for i := 0; i < b.N; i++ {
 		_ = benchLS.String()
 	}
 }
 
+func BenchmarkLSColorsStringHuge(b *testing.B) {
+	ls, err := ParseLSColors(strings.Join(hugeLSCOLOR, ":"))
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.SetBytes(int64(ls.SerializedLen()))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = ls.String()
+	}
+}
+
 // Copy of my enormous LS_COLORS (generated with the excellent github.com/sharkdp/vivid)
 var hugeLSCOLOR = []string{
 	"bd=0;38;2;138;190;183;48;2;51;51;51",
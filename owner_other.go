@@ -0,0 +1,11 @@
+//go:build !unix
+
+package lscolors
+
+import "io/fs"
+
+// ownedByCurrentUser reports if the file fi is owned by the current user.
+// File ownership is not supported on this platform.
+func ownedByCurrentUser(fi fs.FileInfo) (owned, ok bool) {
+	return false, false
+}
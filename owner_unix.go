@@ -0,0 +1,22 @@
+//go:build unix
+
+package lscolors
+
+import (
+	"io/fs"
+	"os"
+	"sync"
+	"syscall"
+)
+
+var currentUID = sync.OnceValue(os.Getuid)
+
+// ownedByCurrentUser reports if the file fi is owned by the current user.
+// The second result is false if the owner is unknown.
+func ownedByCurrentUser(fi fs.FileInfo) (owned, ok bool) {
+	st, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok {
+		return false, false
+	}
+	return int(st.Uid) == currentUID(), true
+}
@@ -0,0 +1,56 @@
+//go:build unix
+
+package lscolors
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+)
+
+// ownerInfo is a FileInfo that reports being owned by uid.
+type ownerInfo struct {
+	fs.FileInfo
+	uid uint32
+}
+
+func (fi ownerInfo) Sys() any { return &syscall.Stat_t{Uid: fi.uid} }
+
+func TestMatchInfoOwner(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "file.go")
+	if err := os.WriteFile(name, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	fi, err := os.Lstat(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := fi.Sys().(*syscall.Stat_t); !ok {
+		t.Skipf("unsupported FileInfo.Sys type: %T", fi.Sys())
+	}
+	ls, err := ParseLSColors("di=01;34:*.go=32")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e := ls.MatchInfo(name, fi); e.Seq != "32" {
+		t.Errorf("MatchInfo = %q; want: %q", e.Seq, "32")
+	}
+
+	ls.OwnedColor = ColorExtension{Seq: "01"}
+	ls.OtherColor = ColorExtension{Seq: "02"}
+	if e := ls.MatchInfo(name, fi); e != &ls.OwnedColor {
+		t.Errorf("MatchInfo = %+v; want: %+v", e, ls.OwnedColor)
+	}
+	other := ownerInfo{FileInfo: fi, uid: uint32(os.Getuid()) + 1}
+	if e := ls.MatchInfo(name, other); e != &ls.OtherColor {
+		t.Errorf("MatchInfo(other) = %+v; want: %+v", e, ls.OtherColor)
+	}
+
+	// Unset colors fall back to the normal rules.
+	ls.OwnedColor = ColorExtension{}
+	if e := ls.MatchInfo(name, fi); e.Seq != "32" {
+		t.Errorf("MatchInfo = %q; want: %q", e.Seq, "32")
+	}
+}
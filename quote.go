@@ -0,0 +1,157 @@
+package lscolors
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// A QuotingStyle controls how file names containing control characters,
+// which could otherwise be used to corrupt or manipulate the terminal, are
+// written. The styles are modeled after those of GNU ls --quoting-style.
+type QuotingStyle int
+
+const (
+	// QuoteNone writes names unmodified.
+	QuoteNone QuotingStyle = iota
+
+	// QuoteEscape escapes control characters and backslashes with C style
+	// backslash escapes such as "\n" and "\033", like ls -b.
+	QuoteEscape
+
+	// QuoteShell quotes names that contain control characters or shell
+	// metacharacters so that they can be pasted into a shell, using $'...'
+	// for control characters, like ls --quoting-style=shell-escape.
+	QuoteShell
+)
+
+// QuoteName returns name quoted according to style. Control characters
+// include the C1 controls, such as U+009B which some terminals interpret
+// as CSI, and the bytes of name that are not valid UTF-8 are escaped as
+// well.
+func QuoteName(name string, style QuotingStyle) string {
+	switch style {
+	case QuoteEscape:
+		if !needsEscape(name) {
+			return name
+		}
+		var b []byte
+		for i := 0; i < len(name); {
+			n, ctrl := scanChar(name[i:])
+			if ctrl || name[i] == '\\' {
+				b = appendEscapes(b, name[i:i+n])
+			} else {
+				b = append(b, name[i:i+n]...)
+			}
+			i += n
+		}
+		return string(b)
+	case QuoteShell:
+		return quoteShell(name)
+	}
+	return name
+}
+
+func isControl(c byte) bool { return c < ' ' || c == 0x7f }
+
+// scanChar returns the length of the character at the start of s, which
+// must not be empty, and if it is a C0 or C1 control character or an
+// invalid UTF-8 byte.
+func scanChar(s string) (n int, ctrl bool) {
+	if c := s[0]; c < utf8.RuneSelf {
+		return 1, isControl(c)
+	}
+	r, n := utf8.DecodeRuneInString(s)
+	return n, r == utf8.RuneError && n == 1 || 0x80 <= r && r <= 0x9f
+}
+
+func needsEscape(name string) bool {
+	for i := 0; i < len(name); {
+		n, ctrl := scanChar(name[i:])
+		if ctrl || name[i] == '\\' {
+			return true
+		}
+		i += n
+	}
+	return false
+}
+
+// appendEscapes appends the C style escape of every byte of s, which is a
+// control character or a backslash, to b. Bytes without a single letter
+// escape, including those of multi-byte characters, are escaped in octal.
+func appendEscapes(b []byte, s string) []byte {
+	for i := 0; i < len(s); i++ {
+		var e byte
+		switch c := s[i]; c {
+		case '\a':
+			e = 'a'
+		case '\b':
+			e = 'b'
+		case '\f':
+			e = 'f'
+		case '\n':
+			e = 'n'
+		case '\r':
+			e = 'r'
+		case '\t':
+			e = 't'
+		case '\v':
+			e = 'v'
+		case '\\':
+			e = '\\'
+		default:
+			b = append(b, '\\', '0'+c>>6, '0'+c>>3&7, '0'+c&7)
+			continue
+		}
+		b = append(b, '\\', e)
+	}
+	return b
+}
+
+// shellSafe reports if c does not need to be quoted in a shell word.
+func shellSafe(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || isDigit(c) ||
+		strings.IndexByte("%+,-./:=@_^", c) >= 0 || c >= utf8.RuneSelf
+}
+
+func quoteShell(name string) string {
+	safe := name != ""
+	for i := 0; i < len(name) && safe; {
+		n, ctrl := scanChar(name[i:])
+		safe = !ctrl && shellSafe(name[i])
+		i += n
+	}
+	if safe {
+		return name
+	}
+	b := make([]byte, 0, len(name)+2)
+	b = append(b, '\'')
+	for i := 0; i < len(name); {
+		n, ctrl := scanChar(name[i:])
+		switch {
+		case ctrl:
+			b = append(b, `'$'`...)
+			for ctrl {
+				b = appendEscapes(b, name[i:i+n])
+				if i += n; i == len(name) {
+					break
+				}
+				n, ctrl = scanChar(name[i:])
+			}
+			b = append(b, `''`...)
+			continue
+		case name[i] == '\'':
+			b = append(b, `'\''`...)
+		default:
+			b = append(b, name[i:i+n]...)
+		}
+		i += n
+	}
+	b = append(b, '\'')
+	// Remove the empty quotes left by leading or trailing control characters.
+	s := string(b)
+	if len(s) > 2 {
+		s = strings.TrimPrefix(s, "''")
+		s = strings.TrimSuffix(s, "''")
+	}
+	return s
+}
@@ -0,0 +1,62 @@
+package lscolors
+
+import (
+	"os"
+	"testing"
+)
+
+func TestQuoteName(t *testing.T) {
+	tests := []struct {
+		name          string
+		escape, shell string
+	}{
+		{"file.go", "file.go", "file.go"},
+		{"日本語", "日本語", "日本語"},
+		{"a b", "a b", "'a b'"},
+		{"it's", "it's", `'it'\''s'`},
+		{`a\b`, `a\\b`, `'a\b'`},
+		{"a\nb", `a\nb`, `'a'$'\n''b'`},
+		{"a\tb\n", `a\tb\n`, `'a'$'\t''b'$'\n'`},
+		{"\x1b[31mred", `\033[31mred`, `$'\033''[31mred'`},
+		{"del\x7f", `del\177`, `'del'$'\177'`},
+		{"csi\u009b31m", `csi\302\23331m`, `'csi'$'\302\233''31m'`},
+		{"bad\xff\xfe.go", `bad\377\376.go`, `'bad'$'\377\376''.go'`},
+		{"\xe6\x97", `\346\227`, `$'\346\227'`},
+		{"\ufffd", "\ufffd", "\ufffd"},
+		{"", "", "''"},
+	}
+	for _, x := range tests {
+		if got := QuoteName(x.name, QuoteNone); got != x.name {
+			t.Errorf("QuoteName(%q, QuoteNone) = %q; want: %q", x.name, got, x.name)
+		}
+		if got := QuoteName(x.name, QuoteEscape); got != x.escape {
+			t.Errorf("QuoteName(%q, QuoteEscape) = %q; want: %q", x.name, got, x.escape)
+		}
+		if got := QuoteName(x.name, QuoteShell); got != x.shell {
+			t.Errorf("QuoteName(%q, QuoteShell) = %q; want: %q", x.name, got, x.shell)
+		}
+	}
+}
+
+func TestFormatterQuote(t *testing.T) {
+	ls, err := ParseLSColors("*.go=32")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := NewFormatter(ls, nil, ColorAlways)
+	f.Quote = QuoteEscape
+	d := testDirEntry{name: "evil\x1b]0;title\a.go"}
+	const want = "\x1b[32mevil\\033]0;title\\a.go\x1b[0m"
+	if s := f.FormatEntry(d.name, d); s != want {
+		t.Errorf("FormatEntry = %q; want: %q", s, want)
+	}
+	if s := string(f.AppendEntry(nil, d.name, d)); s != want {
+		t.Errorf("AppendEntry = %q; want: %q", s, want)
+	}
+
+	f = NewFormatter(ls, os.Stdout, ColorNever)
+	f.Quote = QuoteShell
+	if s, want := f.FormatEntry(d.name, d), `'evil'$'\033'']0;title'$'\a''.go'`; s != want {
+		t.Errorf("FormatEntry = %q; want: %q", s, want)
+	}
+}
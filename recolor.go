@@ -0,0 +1,41 @@
+package lscolors
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// Recolor reads lines of file paths from in, such as the output of ls or
+// find, and writes them to out colored by c. Any existing escape sequences
+// are removed from a line before it is checked. Lines that do not name an
+// existing file are written unmodified.
+func Recolor(in io.Reader, out io.Writer, c *LSColors) error {
+	bw := bufio.NewWriter(out)
+	sc := bufio.NewScanner(in)
+	sc.Buffer(make([]byte, 0, 4096), 1024*1024)
+	var b []byte
+	for sc.Scan() {
+		line := sc.Text()
+		path := Strip(line)
+		b = b[:0]
+		if fi, err := os.Lstat(path); err == nil {
+			dir, base := filepath.Split(path)
+			if dir != "" {
+				b = c.DI.AppendFormat(b, dir)
+			}
+			b = c.MatchInfo(path, fi).AppendFormat(b, base)
+		} else {
+			b = append(b, line...)
+		}
+		b = append(b, '\n')
+		if _, err := bw.Write(b); err != nil {
+			return err
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
@@ -0,0 +1,41 @@
+package lscolors
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRecolor(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "main.go")
+	if err := os.WriteFile(file, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	ls, err := ParseLSColors("di=01;34:*.go=32")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	in := strings.Join([]string{
+		"\x1b[31m" + file + "\x1b[0m", // existing colors are replaced
+		"total 8",
+		"\x1b[31mnot a file\x1b[0m",
+		dir,
+	}, "\n")
+	var out strings.Builder
+	if err := Recolor(strings.NewReader(in), &out, ls); err != nil {
+		t.Fatal(err)
+	}
+	parent, base := filepath.Split(dir)
+	want := strings.Join([]string{
+		ls.DI.Format(dir+string(filepath.Separator)) + "\x1b[32mmain.go\x1b[0m",
+		"total 8",
+		"\x1b[31mnot a file\x1b[0m",
+		ls.DI.Format(parent) + ls.DI.Format(base),
+	}, "\n") + "\n"
+	if out.String() != want {
+		t.Errorf("Recolor:\ngot:  %q\nwant: %q", out.String(), want)
+	}
+}
@@ -0,0 +1,234 @@
+package lscolors
+
+import (
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// parseSGR parses the semicolon separated parameters of an SGR sequence.
+// It returns false if seq contains anything other than digits and ';'.
+// Empty parameters are treated as 0 as they are by terminals.
+func parseSGR(seq string) ([]int, bool) {
+	if seq == "" {
+		return nil, true
+	}
+	params := make([]int, 0, strings.Count(seq, ";")+1)
+	for len(seq) > 0 {
+		var s string
+		if i := strings.IndexByte(seq, ';'); i >= 0 {
+			s, seq = seq[:i], seq[i+1:]
+		} else {
+			s, seq = seq, ""
+		}
+		n := 0
+		for i := 0; i < len(s); i++ {
+			if !isDigit(s[i]) {
+				return nil, false
+			}
+			n = n*10 + int(s[i]-'0')
+			if n > 255 {
+				return nil, false
+			}
+		}
+		params = append(params, n)
+	}
+	return params, true
+}
+
+var colorNames = [8]string{
+	"black", "red", "green", "yellow", "blue", "magenta", "cyan", "white",
+}
+
+var attrNames = [10]string{
+	1: "bold",
+	2: "dim",
+	3: "italic",
+	4: "underline",
+	5: "blink",
+	6: "rapid blink",
+	7: "reverse",
+	8: "hidden",
+	9: "strikethrough",
+}
+
+// extendedColor describes the extended (256 or truecolor) color that starts
+// at params[0] (which should be 38 or 48) and returns the number of params
+// consumed.
+func extendedColor(params []int) (string, int) {
+	if len(params) >= 3 && params[1] == 5 {
+		return "256-color #" + strconv.Itoa(params[2]), 3
+	}
+	if len(params) >= 5 && params[1] == 2 {
+		const hex = "0123456789abcdef"
+		b := []byte{'#', 0, 0, 0, 0, 0, 0}
+		for i, n := range params[2:5] {
+			b[1+i*2] = hex[n>>4]
+			b[2+i*2] = hex[n&0xf]
+		}
+		return string(b), 5
+	}
+	return "", len(params) // malformed: consume the rest
+}
+
+// Describe returns a human readable description of the color sequence such
+// as "bold blue", "white on red" or "underline 256-color #231". Sequences
+// that only set attributes ("underline") or a background ("default on
+// red") are described without a foreground color. Unknown
+// parameters are ignored. If the sequence is not a valid SGR sequence it is
+// returned unmodified.
+func (c *ColorExtension) Describe() string {
+	params, ok := parseSGR(c.Seq)
+	if !ok {
+		return c.Seq
+	}
+	var attrs []string
+	var fg, bg string
+	for i := 0; i < len(params); i++ {
+		switch p := params[i]; {
+		case p == 0:
+			attrs, fg, bg = attrs[:0], "", ""
+		case p < len(attrNames):
+			attrs = append(attrs, attrNames[p])
+		case 30 <= p && p <= 37:
+			fg = colorNames[p-30]
+		case 40 <= p && p <= 47:
+			bg = colorNames[p-40]
+		case 90 <= p && p <= 97:
+			fg = "bright " + colorNames[p-90]
+		case 100 <= p && p <= 107:
+			bg = "bright " + colorNames[p-100]
+		case p == 39:
+			fg = ""
+		case p == 49:
+			bg = ""
+		case p == 38 || p == 48:
+			s, n := extendedColor(params[i:])
+			if p == 38 {
+				fg = s
+			} else {
+				bg = s
+			}
+			i += n - 1
+		}
+	}
+	words := attrs
+	if fg != "" {
+		words = append(words, fg)
+	}
+	if bg != "" {
+		if fg == "" {
+			// Background only: make it clear that the text color is unchanged.
+			words = append(words, "default")
+		}
+		words = append(words, "on", bg)
+	}
+	return strings.Join(words, " ")
+}
+
+// Strip returns s with all ANSI escape sequences removed. CSI sequences
+// (such as colors) and OSC sequences (such as hyperlinks) are removed in
+// their entirety as are other two byte and character set escapes.
+func Strip(s string) string {
+	i := strings.IndexByte(s, '\x1b')
+	if i < 0 {
+		return s
+	}
+	b := make([]byte, 0, len(s))
+	for i >= 0 {
+		b = append(b, s[:i]...)
+		s = s[i+escapeLen(s[i:]):]
+		i = strings.IndexByte(s, '\x1b')
+	}
+	b = append(b, s...)
+	return string(b)
+}
+
+// TrimReset returns s without its trailing reset sequence ("\x1b[0m"), such
+// as the one written by Format, so that colored strings can be joined
+// without resetting between them. If s does not end with a reset it is
+// returned unchanged. Unlike Strip, all other sequences are kept.
+func TrimReset(s string) string {
+	return strings.TrimSuffix(s, resetSeq)
+}
+
+// VisibleLen returns the number of runes of s that are displayed, which is
+// the rune count of Strip(s), without allocating.
+func VisibleLen(s string) int {
+	n := 0
+	for {
+		i := strings.IndexByte(s, '\x1b')
+		if i < 0 {
+			return n + utf8.RuneCountInString(s)
+		}
+		n += utf8.RuneCountInString(s[:i])
+		s = s[i+escapeLen(s[i:]):]
+	}
+}
+
+// escapeLen returns the length of the escape sequence at the start of s,
+// which must start with an ESC. Unterminated sequences extend to the end
+// of s.
+func escapeLen(s string) int {
+	if len(s) < 2 {
+		return len(s)
+	}
+	switch s[1] {
+	case '[': // CSI: parameter and intermediate bytes then a final byte
+		for i := 2; i < len(s); i++ {
+			if 0x40 <= s[i] && s[i] <= 0x7e {
+				return i + 1
+			}
+		}
+	case ']': // OSC: terminated by BEL or ST (ESC \)
+		for i := 2; i < len(s); i++ {
+			switch {
+			case s[i] == '\a':
+				return i + 1
+			case s[i] == '\x1b' && i+1 < len(s) && s[i+1] == '\\':
+				return i + 2
+			}
+		}
+	default: // nF: intermediate bytes then a final byte
+		i := 1
+		for i < len(s) && 0x20 <= s[i] && s[i] <= 0x2f {
+			i++
+		}
+		return min(i+1, len(s))
+	}
+	return len(s)
+}
+
+// mapSGR calls fn with every parameter of seq that is not part of an
+// extended (38 or 48) color and returns seq with those parameters replaced
+// by the result of fn. If fn returns "" the parameter is removed. The text
+// of the remaining parameters is preserved. Invalid sequences are returned
+// unmodified.
+func mapSGR(seq string, fn func(p int, s string) string) string {
+	if _, ok := parseSGR(seq); !ok || seq == "" {
+		return seq
+	}
+	parts := strings.Split(seq, ";")
+	out := parts[:0]
+	for i := 0; i < len(parts); i++ {
+		s := parts[i]
+		p, _ := strconv.Atoi(s)
+		if (p == 38 || p == 48) && i+1 < len(parts) {
+			// Copy the extended color unmodified
+			n := len(parts) - i
+			switch parts[i+1] {
+			case "5":
+				n = min(n, 3)
+			case "2":
+				n = min(n, 5)
+			}
+			out = append(out, parts[i:i+n]...)
+			i += n - 1
+			continue
+		}
+		if s = fn(p, s); s != "" {
+			out = append(out, s)
+		}
+	}
+	return strings.Join(out, ";")
+}
@@ -0,0 +1,76 @@
+package lscolors
+
+import "testing"
+
+func TestDescribe(t *testing.T) {
+	tests := []struct {
+		seq, want string
+	}{
+		{"01;34", "bold blue"},
+		{"37;41", "white on red"},
+		{"4;38;5;231", "underline 256-color #231"},
+		{"0;38;2;129;162;190", "#81a2be"},
+		{"1;38;2;204;102;102;48;2;51;51;51", "bold #cc6666 on #333333"},
+		{"01;93;104", "bold bright yellow on bright blue"},
+		{"30;42", "black on green"},
+		{"0", ""},
+		{"", ""},
+		{"1;0;32", "green"},
+		{"\x1b[2J", "\x1b[2J"},
+
+		// Attribute and background only
+		{"4", "underline"},
+		{"7", "reverse"},
+		{"01;04", "bold underline"},
+		{"48;5;234", "default on 256-color #234"},
+		{"4;41", "underline default on red"},
+	}
+	for _, x := range tests {
+		e := ColorExtension{Seq: x.seq}
+		if got := e.Describe(); got != x.want {
+			t.Errorf("Describe(%q) = %q; want: %q", x.seq, got, x.want)
+		}
+	}
+}
+
+func TestStrip(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"plain", "plain"},
+		{"\x1b[01;34mdir\x1b[0m", "dir"},
+		{"a\x1b[38;2;1;2;3mb\x1b[Kc", "abc"},
+		{"\x1b]8;;http://x\x1b\\link\x1b]8;;\a", "link"},
+		{"a\x1b(Bb", "ab"},
+		{"trailing\x1b[1", "trailing"},
+		{"\x1b", ""},
+	}
+	for _, x := range tests {
+		if got := Strip(x.in); got != x.want {
+			t.Errorf("Strip(%q) = %q; want: %q", x.in, got, x.want)
+		}
+	}
+}
+
+func TestTrimReset(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"\x1b[01;34mdir\x1b[0m", "\x1b[01;34mdir"},
+		{"\x1b[0m\x1b[32ma\x1b[0m", "\x1b[0m\x1b[32ma"},
+		{"\x1b[32ma\x1b[0m\x1b[0m", "\x1b[32ma\x1b[0m"},
+		{"\x1b[32ma", "\x1b[32ma"},
+		{"\x1b[32ma\x1b[m", "\x1b[32ma\x1b[m"},
+		{"plain", "plain"},
+		{"", ""},
+	}
+	for _, x := range tests {
+		if got := TrimReset(x.in); got != x.want {
+			t.Errorf("TrimReset(%q) = %q; want: %q", x.in, got, x.want)
+		}
+	}
+	e := ColorExtension{Seq: "01;34"}
+	if got, want := TrimReset(e.Format("a"))+e.Format("b"), "\x1b[01;34ma\x1b[01;34mb\x1b[0m"; got != want {
+		t.Errorf("joined = %q; want: %q", got, want)
+	}
+}
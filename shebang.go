@@ -0,0 +1,94 @@
+package lscolors
+
+import (
+	"bytes"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// shebangMaxSize is the size of the largest file whose interpreter is read
+// by MatchShebang. Scripts are small and this avoids opening large
+// binaries.
+const shebangMaxSize = 1 << 20
+
+// MatchShebang is like MatchInfo but colors executable regular files that
+// start with a "#!" line by their interpreter using c.ShebangRules. The
+// interpreter is the base name of the program in the "#!" line, or the
+// first argument of env(1) if that is the program, such as "bash" for
+// "#!/bin/bash" and "python" for "#!/usr/bin/env python". This requires
+// reading the file at path so it is only done if ShebangRules is set and
+// the file is no larger than 1MiB.
+func (c *LSColors) MatchShebang(path string, fi fs.FileInfo) *ColorExtension {
+	if len(c.ShebangRules) != 0 && fi.Mode().IsRegular() && fi.Mode()&0111 != 0 &&
+		fi.Size() <= shebangMaxSize {
+		if e, ok := c.ShebangRules[readInterpreter(path)]; ok && !e.Empty() {
+			return &e
+		}
+	}
+	return c.MatchInfo(path, fi)
+}
+
+// readInterpreter returns the name of the interpreter in the "#!" line of
+// the file at path or "" if there is none.
+func readInterpreter(path string) string {
+	f, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	var buf [256]byte
+	n, _ := io.ReadFull(f, buf[:])
+	return parseShebang(buf[:n])
+}
+
+// isScript reports if the file at path, with DirEntry d, starts with a "#!"
+// line. The file is read from the FS of d if it came from MatchFS or
+// WalkDirFunc and from the operating system's filesystem otherwise.
+func isScript(path string, d fs.DirEntry) bool {
+	var f fs.File
+	var err error
+	if e, ok := d.(fsEntry); ok {
+		f, err = e.fsys.Open(e.path)
+	} else if path != "" {
+		f, err = os.Open(path)
+	} else {
+		return false
+	}
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	var buf [2]byte
+	_, err = io.ReadFull(f, buf[:])
+	return err == nil && string(buf[:]) == "#!"
+}
+
+// parseShebang returns the name of the interpreter of the "#!" line at the
+// start of b.
+func parseShebang(b []byte) string {
+	line, ok := bytes.CutPrefix(b, []byte("#!"))
+	if !ok {
+		return ""
+	}
+	if i := bytes.IndexByte(line, '\n'); i >= 0 {
+		line = line[:i]
+	}
+	fields := bytes.Fields(line)
+	if len(fields) == 0 {
+		return ""
+	}
+	name := filepath.Base(string(fields[0]))
+	if name == "env" {
+		name = ""
+		for _, f := range fields[1:] {
+			// Skip options, such as -S, and variable assignments.
+			if f[0] != '-' && bytes.IndexByte(f, '=') < 0 {
+				name = filepath.Base(string(f))
+				break
+			}
+		}
+	}
+	return name
+}
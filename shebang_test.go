@@ -0,0 +1,151 @@
+package lscolors
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+)
+
+func TestParseShebang(t *testing.T) {
+	tests := []struct {
+		line, want string
+	}{
+		{"#!/bin/bash\necho hi\n", "bash"},
+		{"#! /bin/sh -e\n", "sh"},
+		{"#!/usr/bin/env python\n", "python"},
+		{"#!/usr/bin/env -S FOO=1 python3 -u\n", "python3"},
+		{"#!/usr/bin/env\n", ""},
+		{"#!\n", ""},
+		{"\x7fELF", ""},
+		{"", ""},
+	}
+	for _, x := range tests {
+		if got := parseShebang([]byte(x.line)); got != x.want {
+			t.Errorf("parseShebang(%q) = %q; want: %q", x.line, got, x.want)
+		}
+	}
+}
+
+func TestMatchShebang(t *testing.T) {
+	dir := t.TempDir()
+	files := []struct {
+		name, data string
+		mode       os.FileMode
+		want       string
+	}{
+		{"build", "#!/bin/bash\nexit 0\n", 0755, "33"},
+		{"tool", "#!/usr/bin/env python\n", 0755, "34"},
+		{"run.sh", "#!/bin/zsh\n", 0755, "01;32"},
+		{"lib.sh", "#!/bin/bash\n", 0644, "35"},
+		{"prog", "\x7fELF", 0755, "01;32"},
+	}
+	ls, err := ParseLSColors("ex=01;32:*.sh=35")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range files {
+		path := filepath.Join(dir, f.name)
+		if err := os.WriteFile(path, []byte(f.data), f.mode); err != nil {
+			t.Fatal(err)
+		}
+		fi, err := os.Lstat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fi.Mode()&0111 == 0 && f.mode&0111 != 0 {
+			t.Skip("file system does not support executable bits")
+		}
+		if e := ls.MatchShebang(path, fi); e != ls.MatchInfo(path, fi) {
+			t.Errorf("%s: MatchShebang without rules = %+v; want: %+v", f.name, e, ls.MatchInfo(path, fi))
+		}
+	}
+
+	ls.ShebangRules = map[string]ColorExtension{
+		"bash":   {Seq: "33"},
+		"python": {Seq: "34"},
+	}
+	for _, f := range files {
+		path := filepath.Join(dir, f.name)
+		fi, err := os.Lstat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if e := ls.MatchShebang(path, fi); e.Seq != f.want {
+			t.Errorf("%s: MatchShebang = %q; want: %q", f.name, e.Seq, f.want)
+		}
+	}
+}
+
+func TestScriptsBeatExecutable(t *testing.T) {
+	dir := t.TempDir()
+	files := []struct {
+		name, data string
+		mode       os.FileMode
+		gnu, want  string // without and with ScriptsBeatExecutable
+	}{
+		{"build.sh", "#!/bin/sh\n", 0755, "01;32", "33"},
+		{"tool.bin", "\x7fELF", 0755, "01;32", "01;32"}, // binary
+		{"run", "#!/bin/sh\n", 0755, "01;32", "01;32"},  // no extension rule
+		{"lib.sh", "", 0644, "33", "33"},
+	}
+	ls, err := ParseLSColors("ex=01;32:*.sh=33:*.bin=34")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range files {
+		path := filepath.Join(dir, f.name)
+		if err := os.WriteFile(path, []byte(f.data), f.mode); err != nil {
+			t.Fatal(err)
+		}
+		fi, err := os.Lstat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fi.Mode()&0111 == 0 && f.mode&0111 != 0 {
+			t.Skip("file system does not support executable bits")
+		}
+		ls.ScriptsBeatExecutable = false
+		if e := ls.MatchInfo(path, fi); e.Seq != f.gnu {
+			t.Errorf("%s: MatchInfo = %q; want: %q", f.name, e.Seq, f.gnu)
+		}
+		ls.ScriptsBeatExecutable = true
+		if e := ls.MatchInfo(path, fi); e.Seq != f.want {
+			t.Errorf("%s: ScriptsBeatExecutable: MatchInfo = %q; want: %q", f.name, e.Seq, f.want)
+		}
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, e := range ls.MatchEntries(dir, entries) {
+		if want := ls.MatchEntry(filepath.Join(dir, entries[i].Name()), entries[i]); e != want {
+			t.Errorf("%s: MatchEntries = %q; want: %q", entries[i].Name(), e.Seq, want.Seq)
+		}
+	}
+}
+
+func TestScriptsBeatExecutableFS(t *testing.T) {
+	// The files only exist in fsys so they must not be read from the
+	// operating system's filesystem.
+	fsys := fstest.MapFS{
+		"build.sh": {Data: []byte("#!/bin/sh\n"), Mode: 0755},
+		"tool.sh":  {Data: []byte("\x7fELF"), Mode: 0755},
+	}
+	ls, err := ParseLSColors("ex=01;32:*.sh=33")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ls.ScriptsBeatExecutable = true
+	want := map[string]string{"build.sh": "33", "tool.sh": "01;32"}
+	for name, seq := range want {
+		e, err := ls.MatchFS(fsys, name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if e.Seq != seq {
+			t.Errorf("MatchFS(%q) = %q; want: %q", name, e.Seq, seq)
+		}
+	}
+}
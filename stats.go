@@ -0,0 +1,60 @@
+package lscolors
+
+import "io/fs"
+
+// MatchStats counts the rules that matched files, which is useful for
+// profiling a workload or debugging why files are not colored. It is
+// updated by MatchEntryStats and is not safe for concurrent use.
+type MatchStats struct {
+	Dir        int // di
+	Link       int // ln
+	Orphan     int // or: broken symbolic links
+	Executable int // ex
+	Extension  int // extension, name, glob and type rules
+	File       int // fi: regular files that no other rule matched
+	NoMatch    int // no or no color at all
+	Other      int // all other rules, such as pi or HiddenColor
+}
+
+// Total returns the number of files counted by s.
+func (s *MatchStats) Total() int {
+	return s.Dir + s.Link + s.Orphan + s.Executable + s.Extension + s.File +
+		s.NoMatch + s.Other
+}
+
+// MatchEntryStats is like MatchEntry but also counts the rule that matched
+// in stats, if it is not nil.
+func (c *LSColors) MatchEntryStats(path string, d fs.DirEntry, stats *MatchStats) *ColorExtension {
+	ext, key := c.match(path, d.Name(), d.Type(), d)
+	if stats != nil {
+		stats.add(c, ext, key)
+	}
+	return ext
+}
+
+func (s *MatchStats) add(c *LSColors, ext *ColorExtension, key string) {
+	switch key {
+	case "di":
+		s.Dir++
+	case "ln":
+		s.Link++
+	case "or":
+		s.Orphan++
+	case "ex":
+		s.Executable++
+	case extKey:
+		s.Extension++
+	case "fi":
+		s.File++
+	case "no", "":
+		s.NoMatch++
+	default:
+		for i := range c.Globs {
+			if ext == &c.Globs[i] {
+				s.Extension++
+				return
+			}
+		}
+		s.Other++
+	}
+}
@@ -0,0 +1,57 @@
+package lscolors
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMatchEntryStats(t *testing.T) {
+	root := makeTestTree(t, "a.go", "b/c.go", "b/d/", "e.txt", "lib.so.1", "README", ".hidden")
+	if err := os.Symlink("a.go", filepath.Join(root, "link")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("missing", filepath.Join(root, "broken")); err != nil {
+		t.Fatal(err)
+	}
+	ls, err := ParseLSColors("di=01;34:ln=01;36:or=31:ex=01;32:fi=0:*.go=32:*.[0-9]*=33")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ls.HiddenColor = ColorExtension{Ext: "hidden", Seq: "02"}
+
+	var stats MatchStats
+	err = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if e := ls.MatchEntryStats(path, d, &stats); e != ls.MatchEntry(path, d) {
+			t.Errorf("MatchEntryStats(%q) = %+v; want: %+v", path, e, ls.MatchEntry(path, d))
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// DirEntry.Type does not include permission bits
+	ls.MatchEntryStats("run", testDirEntry{name: "run", mode: 0755}, &stats)
+
+	want := MatchStats{
+		Dir:        3, // root, b and b/d
+		Link:       1,
+		Orphan:     1,
+		Executable: 1,
+		Extension:  3, // a.go, b/c.go and lib.so.1
+		File:       2, // e.txt and README
+		Other:      1, // .hidden
+	}
+	if stats != want {
+		t.Errorf("stats = %+v; want: %+v", stats, want)
+	}
+	if n := stats.Total(); n != 12 {
+		t.Errorf("Total() = %d; want: %d", n, 12)
+	}
+
+	ls.MatchEntryStats("x", testDirEntry{name: "x"}, nil) // nil stats is allowed
+}
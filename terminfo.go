@@ -0,0 +1,457 @@
+package lscolors
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// A Terminfo holds the capabilities of a terminal, read from its compiled
+// terminfo(5) entry, that are used to set colors and attributes. It lets a
+// Formatter support terminals that do not use the ANSI sequences written
+// by Format.
+type Terminfo struct {
+	Names  []string // names of the terminal, the first is its primary name
+	Colors int      // number of colors supported or -1 if unknown
+
+	setaf string // set_a_foreground
+	setab string // set_a_background
+	sgr0  string // exit_attribute_mode
+
+	// attrs are the capabilities that enable the attribute with the
+	// same SGR code, such as attrs[1] (enter_bold_mode).
+	attrs [9]string
+}
+
+// stringCap returns the field of ti that holds the capability with index
+// i in the string section of a compiled entry (see term.h) or nil if it is
+// not used.
+func (ti *Terminfo) stringCap(i int) *string {
+	switch i {
+	case 26: // enter_blink_mode
+		return &ti.attrs[5]
+	case 27: // enter_bold_mode
+		return &ti.attrs[1]
+	case 30: // enter_dim_mode
+		return &ti.attrs[2]
+	case 32: // enter_secure_mode
+		return &ti.attrs[8]
+	case 34: // enter_reverse_mode
+		return &ti.attrs[7]
+	case 36: // enter_underline_mode
+		return &ti.attrs[4]
+	case 39: // exit_attribute_mode
+		return &ti.sgr0
+	case 311: // enter_italics_mode
+		return &ti.attrs[3]
+	case 359: // set_a_foreground
+		return &ti.setaf
+	case 360: // set_a_background
+		return &ti.setab
+	}
+	return nil
+}
+
+// terminfoColors is the index of max_colors in the numbers section.
+const terminfoColors = 13
+
+// ErrNoTerminfo is returned by LoadTerminfo if no entry exists for a
+// terminal.
+var ErrNoTerminfo = errors.New("lscolors: terminfo entry not found")
+
+// terminfoDirs returns the directories searched for terminfo entries, in
+// the order used by ncurses.
+func terminfoDirs() []string {
+	var dirs []string
+	if dir := os.Getenv("TERMINFO"); dir != "" {
+		dirs = append(dirs, dir)
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		dirs = append(dirs, filepath.Join(home, ".terminfo"))
+	}
+	system := []string{"/etc/terminfo", "/lib/terminfo", "/usr/share/terminfo"}
+	if s := os.Getenv("TERMINFO_DIRS"); s != "" {
+		for _, dir := range strings.Split(s, ":") {
+			if dir == "" {
+				dirs = append(dirs, system...) // empty means the default
+			} else {
+				dirs = append(dirs, dir)
+			}
+		}
+		return dirs
+	}
+	return append(dirs, system...)
+}
+
+// LoadTerminfo loads the terminfo entry of the terminal named term, which
+// is usually the value of the TERM environment variable, from the
+// directories searched by ncurses: $TERMINFO, ~/.terminfo, $TERMINFO_DIRS
+// and the system directories. ErrNoTerminfo is returned if no entry
+// exists.
+func LoadTerminfo(term string) (*Terminfo, error) {
+	if term == "" || strings.ContainsAny(term, "/\\") || term == "." || term == ".." {
+		return nil, ErrNoTerminfo
+	}
+	for _, dir := range terminfoDirs() {
+		// Entries are stored by their first character or, on macOS, its
+		// hex value.
+		for _, sub := range []string{term[:1], strconv.FormatInt(int64(term[0]), 16)} {
+			data, err := os.ReadFile(filepath.Join(dir, sub, term))
+			if err == nil {
+				return ParseTerminfo(data)
+			}
+		}
+	}
+	return nil, ErrNoTerminfo
+}
+
+// ParseTerminfo parses a compiled terminfo entry in the legacy or the
+// extended number format. Extended capabilities are ignored.
+func ParseTerminfo(data []byte) (*Terminfo, error) {
+	errInvalid := errors.New("lscolors: invalid terminfo entry")
+	if len(data) < 12 {
+		return nil, errInvalid
+	}
+	var h [6]int
+	for i := range h {
+		h[i] = int(int16(binary.LittleEndian.Uint16(data[i*2:])))
+	}
+	numSize := 2
+	switch h[0] {
+	case 0432:
+	case 01036:
+		numSize = 4
+	default:
+		return nil, fmt.Errorf("lscolors: invalid terminfo magic number: %#o", h[0])
+	}
+	nameSize, boolCount, numCount, strCount, tableSize := h[1], h[2], h[3], h[4], h[5]
+	if nameSize < 0 || boolCount < 0 || numCount < 0 || strCount < 0 || tableSize < 0 {
+		return nil, errInvalid
+	}
+	off := 12 + nameSize + boolCount
+	off += off & 1 // numbers are aligned to an even offset
+	strOff := off + numCount*numSize
+	tableOff := strOff + strCount*2
+	if len(data) < tableOff+tableSize || nameSize == 0 {
+		return nil, errInvalid
+	}
+
+	ti := &Terminfo{Colors: -1}
+	names := string(data[12 : 12+nameSize-1]) // NUL terminated
+	ti.Names = strings.Split(names, "|")
+	if numCount > terminfoColors {
+		p := data[off+terminfoColors*numSize:]
+		if numSize == 2 {
+			ti.Colors = int(int16(binary.LittleEndian.Uint16(p)))
+		} else {
+			ti.Colors = int(int32(binary.LittleEndian.Uint32(p)))
+		}
+		if ti.Colors < 0 {
+			ti.Colors = -1
+		}
+	}
+	table := data[tableOff : tableOff+tableSize]
+	for i := 0; i < strCount; i++ {
+		field := ti.stringCap(i)
+		if field == nil {
+			continue
+		}
+		n := int(int16(binary.LittleEndian.Uint16(data[strOff+i*2:])))
+		if n < 0 {
+			continue // absent or cancelled
+		}
+		if n >= len(table) {
+			return nil, errInvalid
+		}
+		s := string(table[n:])
+		if j := strings.IndexByte(s, 0); j >= 0 {
+			s = s[:j]
+		}
+		*field = stripPadding(s)
+	}
+	return ti, nil
+}
+
+// stripPadding removes the "$<5>" style delays from a capability.
+func stripPadding(s string) string {
+	for {
+		i := strings.Index(s, "$<")
+		if i < 0 {
+			return s
+		}
+		j := strings.IndexByte(s[i:], '>')
+		if j < 0 {
+			return s
+		}
+		s = s[:i] + s[i+j+1:]
+	}
+}
+
+// Sequence returns the terminal specific escape sequence that sets the
+// colors and attributes of the SGR sequence seq and the sequence that
+// resets them. Colors that the terminal does not support are replaced by
+// the closest supported color. It returns false if seq is not a valid SGR
+// sequence or uses an attribute that the terminal does not support, in
+// which case the ANSI sequence may be used instead.
+func (ti *Terminfo) Sequence(seq string) (set, reset string, ok bool) {
+	params, ok := parseSGR(seq)
+	if !ok || ti.sgr0 == "" {
+		return "", "", false
+	}
+	var b strings.Builder
+	color := func(c string, n int) bool {
+		if c == "" {
+			return false
+		}
+		if ti.Colors > 0 && n >= ti.Colors {
+			if n >= 16 {
+				n = rgbTo16(color256ToRGB(n))
+			}
+			if n >= ti.Colors {
+				n %= 8
+			}
+		}
+		b.WriteString(tparm(c, n))
+		return true
+	}
+	for i := 0; i < len(params); i++ {
+		p := params[i]
+		switch {
+		case p == 0:
+			b.WriteString(ti.sgr0)
+		case 0 < p && p < len(ti.attrs):
+			if ti.attrs[p] == "" {
+				return "", "", false
+			}
+			b.WriteString(ti.attrs[p])
+		case 30 <= p && p <= 37, 90 <= p && p <= 97:
+			if !color(ti.setaf, sgrIndex(p)) {
+				return "", "", false
+			}
+		case 40 <= p && p <= 47, 100 <= p && p <= 107:
+			if !color(ti.setab, sgrIndex(p)) {
+				return "", "", false
+			}
+		case p == 38 || p == 48:
+			c := ti.setaf
+			if p == 48 {
+				c = ti.setab
+			}
+			var n int
+			switch {
+			case i+2 < len(params) && params[i+1] == 5:
+				n = params[i+2]
+				i += 2
+			case i+4 < len(params) && params[i+1] == 2:
+				n = rgbTo256([3]uint8{uint8(params[i+2]), uint8(params[i+3]), uint8(params[i+4])})
+				i += 4
+			default:
+				return "", "", false
+			}
+			if !color(c, n) {
+				return "", "", false
+			}
+		default:
+			return "", "", false
+		}
+	}
+	return b.String(), ti.sgr0, true
+}
+
+// sgrIndex returns the color index (0-15) of the standard foreground or
+// background SGR color code p.
+func sgrIndex(p int) int {
+	if p >= 90 {
+		return (p-90)%10 + 8
+	}
+	return (p - 30) % 10
+}
+
+// tparm evaluates the parameterized capability s with the integer
+// parameters params, like tparm(3).
+func tparm(s string, params ...int) string {
+	var (
+		out   []byte
+		stack []int
+		vars  [52]int
+		p     [9]int
+	)
+	copy(p[:], params)
+	push := func(v int) { stack = append(stack, v) }
+	pop := func() int {
+		if len(stack) == 0 {
+			return 0
+		}
+		v := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		return v
+	}
+	varIndex := func(c byte) int {
+		switch {
+		case 'a' <= c && c <= 'z':
+			return int(c - 'a')
+		case 'A' <= c && c <= 'Z':
+			return int(c-'A') + 26
+		}
+		return -1
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] != '%' {
+			out = append(out, s[i])
+			continue
+		}
+		i++
+		if i == len(s) {
+			break
+		}
+		// Formatted output: %[[:]flags][width[.precision]][doxXs]
+		if j := i; strings.IndexByte(":-+# .0123456789doxXs", s[i]) >= 0 {
+			for j < len(s) && strings.IndexByte(":-+# .0123456789", s[j]) >= 0 {
+				j++
+			}
+			if j < len(s) && strings.IndexByte("doxXs", s[j]) >= 0 {
+				spec := strings.TrimPrefix(s[i:j], ":")
+				verb := s[j]
+				if verb == 's' {
+					verb = 'd' // string parameters are not supported
+				}
+				out = fmt.Appendf(out, "%"+spec+string(verb), pop())
+				i = j
+				continue
+			}
+		}
+		switch c := s[i]; c {
+		case '%':
+			out = append(out, '%')
+		case 'c':
+			out = append(out, byte(pop()))
+		case 'p':
+			if i+1 < len(s) && '1' <= s[i+1] && s[i+1] <= '9' {
+				i++
+				push(p[s[i]-'1'])
+			}
+		case 'P', 'g':
+			if i+1 < len(s) {
+				i++
+				if n := varIndex(s[i]); n >= 0 {
+					if c == 'P' {
+						vars[n] = pop()
+					} else {
+						push(vars[n])
+					}
+				}
+			}
+		case '\'':
+			if i+2 < len(s) {
+				push(int(s[i+1]))
+				i += 2
+			}
+		case '{':
+			j := strings.IndexByte(s[i:], '}')
+			if j < 0 {
+				return string(out)
+			}
+			n, _ := strconv.Atoi(s[i+1 : i+j])
+			push(n)
+			i += j
+		case 'l':
+			pop()
+			push(0)
+		case 'i':
+			p[0]++
+			p[1]++
+		case '+', '-', '*', '/', 'm', '&', '|', '^', '=', '>', '<', 'A', 'O':
+			b, a := pop(), pop()
+			push(tparmOp(c, a, b))
+		case '!':
+			if pop() == 0 {
+				push(1)
+			} else {
+				push(0)
+			}
+		case '~':
+			push(^pop())
+		case 't':
+			if pop() == 0 {
+				i = skipCond(s, i, true)
+			}
+		case 'e':
+			i = skipCond(s, i, false)
+		}
+	}
+	return string(out)
+}
+
+// tparmOp applies the binary operator op to a and b.
+func tparmOp(op byte, a, b int) int {
+	bool2int := func(v bool) int {
+		if v {
+			return 1
+		}
+		return 0
+	}
+	switch op {
+	case '+':
+		return a + b
+	case '-':
+		return a - b
+	case '*':
+		return a * b
+	case '/':
+		if b != 0 {
+			return a / b
+		}
+	case 'm':
+		if b != 0 {
+			return a % b
+		}
+	case '&':
+		return a & b
+	case '|':
+		return a | b
+	case '^':
+		return a ^ b
+	case '=':
+		return bool2int(a == b)
+	case '>':
+		return bool2int(a > b)
+	case '<':
+		return bool2int(a < b)
+	case 'A':
+		return bool2int(a != 0 && b != 0)
+	case 'O':
+		return bool2int(a != 0 || b != 0)
+	}
+	return 0
+}
+
+// skipCond returns the index of the end of the conditional branch that
+// starts after s[i]: the 'e' of the matching "%e", if else is true, or the
+// ';' of the matching "%;".
+func skipCond(s string, i int, toElse bool) int {
+	level := 0
+	for i++; i < len(s); i++ {
+		if s[i] != '%' || i+1 == len(s) {
+			continue
+		}
+		i++
+		switch s[i] {
+		case '?':
+			level++
+		case ';':
+			if level == 0 {
+				return i
+			}
+			level--
+		case 'e':
+			if level == 0 && toElse {
+				return i
+			}
+		}
+	}
+	return i
+}
@@ -0,0 +1,218 @@
+package lscolors
+
+import (
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// compileTerminfo returns a compiled terminfo entry in the legacy format
+// with the given max_colors and string capabilities, keyed by their index.
+func compileTerminfo(names string, colors int, strs map[int]string) []byte {
+	nstr := 0
+	for i := range strs {
+		nstr = max(nstr, i+1)
+	}
+	var table []byte
+	offsets := make([]int16, nstr)
+	for i := range offsets {
+		offsets[i] = -1
+		if s, ok := strs[i]; ok {
+			offsets[i] = int16(len(table))
+			table = append(append(table, s...), 0)
+		}
+	}
+	le := binary.LittleEndian
+	b := le.AppendUint16(nil, 0432)
+	for _, n := range []int{len(names) + 1, 1, terminfoColors + 1, nstr, len(table)} {
+		b = le.AppendUint16(b, uint16(n))
+	}
+	b = append(append(b, names...), 0)
+	b = append(b, 1) // a single boolean
+	if len(b)%2 != 0 {
+		b = append(b, 0)
+	}
+	for i := 0; i <= terminfoColors; i++ {
+		n := -1
+		if i == terminfoColors {
+			n = colors
+		}
+		b = le.AppendUint16(b, uint16(int16(n)))
+	}
+	for _, off := range offsets {
+		b = le.AppendUint16(b, uint16(off))
+	}
+	return append(b, table...)
+}
+
+// xtermCaps are the capabilities of xterm-256color.
+var xtermCaps = map[int]string{
+	27:  "\x1b[1m",
+	36:  "\x1b[4m",
+	39:  "\x1b(B\x1b[m",
+	311: "\x1b[3m",
+	359: "\x1b[%?%p1%{8}%<%t3%p1%d%e%p1%{16}%<%t9%p1%{8}%-%d%e38;5;%p1%d%;m",
+	360: "\x1b[%?%p1%{8}%<%t4%p1%d%e%p1%{16}%<%t10%p1%{8}%-%d%e48;5;%p1%d%;m",
+}
+
+func TestTparm(t *testing.T) {
+	tests := []struct {
+		s      string
+		params []int
+		want   string
+	}{
+		{xtermCaps[359], []int{1}, "\x1b[31m"},
+		{xtermCaps[359], []int{12}, "\x1b[94m"},
+		{xtermCaps[359], []int{208}, "\x1b[38;5;208m"},
+		{xtermCaps[360], []int{4}, "\x1b[44m"},
+		{"\x1b[%i%p1%d;%p2%dH", []int{4, 9}, "\x1b[5;10H"},
+		{"%p1%02d|%p1%x|%p1%:-3d|", []int{10}, "10|a|10 |"},
+		{"%p1%Pa%ga%ga%+%d", []int{21}, "42"},
+		{"%'A'%c%%", nil, "A%"},
+		{"%?%p1%{1}%=%tone%e%p1%{2}%=%ttwo%eother%;", []int{2}, "two"},
+		{"%?%p1%t%?%p2%ta%;b%ec%;", []int{0, 1}, "c"},
+		{"%?%p1%t%?%p2%ta%;b%ec%;", []int{1, 0}, "b"},
+		{"%?%p1%t%?%p2%ta%;b%ec%;", []int{1, 1}, "ab"},
+		{"%p1%!%d%p1%~%d", []int{0}, "1-1"},
+	}
+	for _, x := range tests {
+		if got := tparm(x.s, x.params...); got != x.want {
+			t.Errorf("tparm(%q, %v) = %q; want: %q", x.s, x.params, got, x.want)
+		}
+	}
+}
+
+func TestParseTerminfo(t *testing.T) {
+	data := compileTerminfo("xterm-256color|xterm with 256 colors", 256, xtermCaps)
+	ti, err := ParseTerminfo(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ti.Names[0] != "xterm-256color" || len(ti.Names) != 2 {
+		t.Errorf("Names = %q", ti.Names)
+	}
+	if ti.Colors != 256 {
+		t.Errorf("Colors = %d; want: %d", ti.Colors, 256)
+	}
+	if ti.setaf != xtermCaps[359] || ti.sgr0 != xtermCaps[39] || ti.attrs[1] != xtermCaps[27] {
+		t.Errorf("unexpected capabilities: %+v", ti)
+	}
+
+	// Every truncation must be rejected
+	for i := 0; i < len(data); i++ {
+		if _, err := ParseTerminfo(data[:i]); err == nil {
+			t.Errorf("ParseTerminfo(data[:%d]): expected error", i)
+		}
+	}
+	if _, err := ParseTerminfo(append([]byte{0, 0}, data[2:]...)); err == nil {
+		t.Error("ParseTerminfo: expected error for invalid magic number")
+	}
+}
+
+func TestTerminfoSequence(t *testing.T) {
+	// A terminal with 8 colors that uses its own sequences
+	ti, err := ParseTerminfo(compileTerminfo("odd", 8, map[int]string{
+		27:  "<bold$<2>>",
+		39:  "<reset>",
+		359: "<fg%p1%d>",
+		360: "<bg%p1%d>",
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		seq  string
+		want string
+		ok   bool
+	}{
+		{"01;34", "<bold><fg4>", true},
+		{"30;43", "<fg0><bg3>", true},
+		{"0;01", "<reset><bold>", true},
+		{"94", "<fg4>", true},           // bright blue
+		{"38;5;196", "<fg1>", true},     // 256 color red
+		{"38;2;0;255;0", "<fg2>", true}, // truecolor green
+		{"04;34", "", false},            // no underline
+		{"38;5", "", false},             // malformed
+		{"bad", "", false},
+	}
+	for _, x := range tests {
+		set, reset, ok := ti.Sequence(x.seq)
+		if set != x.want || ok != x.ok || (ok && reset != "<reset>") {
+			t.Errorf("Sequence(%q) = %q, %q, %t; want: %q, %q, %t",
+				x.seq, set, reset, ok, x.want, "<reset>", x.ok)
+		}
+	}
+}
+
+func TestLoadTerminfo(t *testing.T) {
+	dir := t.TempDir()
+	data := compileTerminfo("mock-term", 256, xtermCaps)
+	for _, path := range []string{"m/mock-term", "6e/nock-term"} {
+		path = filepath.Join(dir, filepath.FromSlash(path))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Setenv("TERMINFO", dir)
+	t.Setenv("TERMINFO_DIRS", dir)
+	for _, term := range []string{"mock-term", "nock-term"} {
+		ti, err := LoadTerminfo(term)
+		if err != nil {
+			t.Fatalf("LoadTerminfo(%q): %v", term, err)
+		}
+		if ti.Names[0] != "mock-term" {
+			t.Errorf("LoadTerminfo(%q): Names = %q", term, ti.Names)
+		}
+	}
+	for _, term := range []string{"", "../m/mock-term", "missing-term"} {
+		if _, err := LoadTerminfo(term); !errors.Is(err, ErrNoTerminfo) {
+			t.Errorf("LoadTerminfo(%q) = %v; want: %v", term, err, ErrNoTerminfo)
+		}
+	}
+}
+
+func TestFormatterTerminfo(t *testing.T) {
+	ls, err := ParseLSColors("di=01;34:*.go=32:*.md=09")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ti, err := ParseTerminfo(compileTerminfo("odd", 8, map[int]string{
+		27:  "<bold>",
+		39:  "<reset>",
+		359: "<fg%p1%d>",
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := &Formatter{Colors: ls, Terminfo: ti, enabled: true}
+	tests := []struct {
+		d    testDirEntry
+		want string
+	}{
+		{testDirEntry{name: "dir", mode: os.ModeDir}, "<bold><fg4>dir<reset>"},
+		{testDirEntry{name: "main.go"}, "<fg2>main.go<reset>"},
+		{testDirEntry{name: "file"}, "file"},
+		// Strikethrough is not supported: fall back to ANSI
+		{testDirEntry{name: "a.md"}, "\x1b[09ma.md\x1b[0m"},
+	}
+	for _, x := range tests {
+		if got := f.FormatEntry(x.d.name, x.d); got != x.want {
+			t.Errorf("FormatEntry(%q) = %q; want: %q", x.d.name, got, x.want)
+		}
+		if got := string(f.AppendEntry(nil, x.d.name, x.d)); got != x.want {
+			t.Errorf("AppendEntry(%q) = %q; want: %q", x.d.name, got, x.want)
+		}
+	}
+
+	// Pager output is always ANSI
+	f.Pager = true
+	d := testDirEntry{name: "main.go"}
+	if got, want := f.FormatEntry("main.go", d), ls.MatchEntry("main.go", d).Format("main.go"); got != want {
+		t.Errorf("FormatEntry with Pager = %q; want: %q", got, want)
+	}
+}
@@ -0,0 +1,116 @@
+package lscolors
+
+import (
+	"bufio"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+)
+
+// TreeOptions configures FormatTree.
+type TreeOptions struct {
+	// MaxDepth limits how deep the tree is descended. The entries of root
+	// are at depth 1. Zero means no limit.
+	MaxDepth int
+
+	// DirsFirst lists directories before other files. Otherwise entries
+	// are sorted by name.
+	DirsFirst bool
+}
+
+// MatchDepth is like MatchEntry but colors directories at the given depth
+// with DepthColors[depth], if it is set. The root of a walk is at depth 0
+// and its entries are at depth 1.
+func (c *LSColors) MatchDepth(path string, d fs.DirEntry, depth int) *ColorExtension {
+	if e := c.depthColor(d.IsDir(), depth); e != nil {
+		return e
+	}
+	return c.MatchEntry(path, d)
+}
+
+// depthColor returns the DepthColors entry for a directory at depth or nil
+// if there is none.
+func (c *LSColors) depthColor(isDir bool, depth int) *ColorExtension {
+	if isDir && 0 <= depth && depth < len(c.DepthColors) && !c.DepthColors[depth].Empty() {
+		return &c.DepthColors[depth]
+	}
+	return nil
+}
+
+// FormatTree writes a colored tree of the directory root to w in the style
+// of tree(1), with box drawing characters connecting the entries. The
+// directories are colored by depth if c.DepthColors is set.
+func (c *LSColors) FormatTree(root string, w io.Writer, opts TreeOptions) error {
+	fi, err := os.Lstat(root)
+	if err != nil {
+		return err
+	}
+	bw := bufio.NewWriter(w)
+	ext := c.depthColor(fi.IsDir(), 0)
+	if ext == nil {
+		ext = c.MatchInfo(root, fi)
+	}
+	b := ext.AppendFormat(nil, root)
+	b = append(b, '\n')
+	if _, err := bw.Write(b); err != nil {
+		return err
+	}
+	if fi.IsDir() {
+		if err := c.formatTree(bw, b[:0], root, nil, 1, &opts); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+func (c *LSColors) formatTree(w *bufio.Writer, b []byte, dir string, prefix []byte,
+	depth int, opts *TreeOptions) error {
+
+	if opts.MaxDepth > 0 && depth > opts.MaxDepth {
+		return nil
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	if opts.DirsFirst {
+		slices.SortStableFunc(entries, func(a, b os.DirEntry) int {
+			switch {
+			case a.IsDir() == b.IsDir():
+				return 0
+			case a.IsDir():
+				return -1
+			}
+			return 1
+		})
+	}
+	for i, d := range entries {
+		last := i == len(entries)-1
+		path := filepath.Join(dir, d.Name())
+		b = append(b[:0], prefix...)
+		if last {
+			b = append(b, "└── "...)
+		} else {
+			b = append(b, "├── "...)
+		}
+		b = c.MatchDepth(path, d, depth).AppendFormat(b, d.Name())
+		b = append(b, '\n')
+		if _, err := w.Write(b); err != nil {
+			return err
+		}
+		if d.IsDir() {
+			p := prefix
+			if last {
+				p = append(p[:len(p):len(p)], "    "...)
+			} else {
+				p = append(p[:len(p):len(p)], "│   "...)
+			}
+			if err := c.formatTree(w, b, path, p, depth+1, opts); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
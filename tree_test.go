@@ -0,0 +1,123 @@
+package lscolors
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// makeTestTree creates the files (directories end with a '/') in a new
+// temporary directory and returns its path.
+func makeTestTree(t *testing.T, files ...string) string {
+	t.Helper()
+	root := t.TempDir()
+	for _, name := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if strings.HasSuffix(name, "/") {
+			if err := os.MkdirAll(path, 0755); err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+func TestFormatTree(t *testing.T) {
+	root := makeTestTree(t, "a.go", "b/c.go", "b/d/", "e.txt", "f/")
+	ls, err := ParseLSColors("di=34:*.go=32")
+	if err != nil {
+		t.Fatal(err)
+	}
+	di := func(s string) string { return "\x1b[34m" + s + "\x1b[0m" }
+	file := func(s string) string { return "\x1b[32m" + s + "\x1b[0m" }
+	no := func(s string) string { return s } // uncolored names have no escapes
+
+	tests := []struct {
+		opts TreeOptions
+		want []string
+	}{
+		{
+			TreeOptions{},
+			[]string{
+				di(root),
+				"├── " + file("a.go"),
+				"├── " + di("b"),
+				"│   ├── " + file("c.go"),
+				"│   └── " + di("d"),
+				"├── " + no("e.txt"),
+				"└── " + di("f"),
+			},
+		},
+		{
+			TreeOptions{DirsFirst: true},
+			[]string{
+				di(root),
+				"├── " + di("b"),
+				"│   ├── " + di("d"),
+				"│   └── " + file("c.go"),
+				"├── " + di("f"),
+				"├── " + file("a.go"),
+				"└── " + no("e.txt"),
+			},
+		},
+		{
+			TreeOptions{MaxDepth: 1},
+			[]string{
+				di(root),
+				"├── " + file("a.go"),
+				"├── " + di("b"),
+				"├── " + no("e.txt"),
+				"└── " + di("f"),
+			},
+		},
+	}
+	for _, x := range tests {
+		var w strings.Builder
+		if err := ls.FormatTree(root, &w, x.opts); err != nil {
+			t.Fatal(err)
+		}
+		want := strings.Join(x.want, "\n") + "\n"
+		if w.String() != want {
+			t.Errorf("FormatTree(%+v):\ngot:\n%s\nwant:\n%s", x.opts, w.String(), want)
+		}
+	}
+}
+
+func TestFormatTreeDepthColors(t *testing.T) {
+	root := makeTestTree(t, "a/b/c/d.go", "a/e.go")
+	ls, err := ParseLSColors("di=34:*.go=32")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ls.DepthColors = []ColorExtension{{Seq: "01;35"}, {Seq: "01;34"}, {}}
+	color := func(seq, s string) string { return "\x1b[" + seq + "m" + s + "\x1b[0m" }
+	want := strings.Join([]string{
+		color("01;35", root),
+		"└── " + color("01;34", "a"),
+		"    ├── " + color("34", "b"),     // empty color
+		"    │   └── " + color("34", "c"), // beyond DepthColors
+		"    │       └── " + color("32", "d.go"),
+		"    └── " + color("32", "e.go"),
+	}, "\n") + "\n"
+	var w strings.Builder
+	if err := ls.FormatTree(root, &w, TreeOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if w.String() != want {
+		t.Errorf("FormatTree:\ngot:\n%s\nwant:\n%s", w.String(), want)
+	}
+
+	// Files are not colored by depth.
+	d := testDirEntry{name: "a.go"}
+	if e := ls.MatchDepth("a.go", d, 1); e.Seq != "32" {
+		t.Errorf("MatchDepth(a.go, 1) = %q; want: %q", e.Seq, "32")
+	}
+}
@@ -0,0 +1,84 @@
+package lscolors
+
+import (
+	"fmt"
+	"io/fs"
+	"strings"
+)
+
+// FileType is the type of file matched by a TypeRule.
+type FileType int
+
+const (
+	TypeRegular       FileType = iota // any regular file
+	TypeExecutable                    // executable regular file
+	TypeNonExecutable                 // non-executable regular file
+	TypeDir                           // directory
+	TypeSymlink                       // symbolic link
+)
+
+// match reports if a file of type typ is of type t.
+func (t FileType) match(typ fs.FileMode) bool {
+	switch t {
+	case TypeRegular:
+		return typ.IsRegular()
+	case TypeExecutable:
+		return typ.IsRegular() && typ&0111 != 0
+	case TypeNonExecutable:
+		return typ.IsRegular() && typ&0111 == 0
+	case TypeDir:
+		return typ.IsDir()
+	case TypeSymlink:
+		return typ&fs.ModeSymlink != 0
+	}
+	return false
+}
+
+// A TypeRule colors files of a given type whose name ends with Ext, for
+// example to color executable shell scripts differently from ones that are
+// not executable.
+type TypeRule struct {
+	Type FileType
+	ColorExtension
+}
+
+// NewTypeRule returns a TypeRule for files of type typ with extension ext,
+// such as ".sh" or "*.sh".
+func NewTypeRule(typ FileType, ext, seq string) (TypeRule, error) {
+	ext = strings.TrimPrefix(ext, "*")
+	if ext == "" {
+		return TypeRule{}, fmt.Errorf("lscolors: empty extension for type rule")
+	}
+	if !validSequence(seq) {
+		return TypeRule{}, fmt.Errorf("lscolors: invalid color sequence for %q: %q", ext, seq)
+	}
+	return TypeRule{Type: typ, ColorExtension: ColorExtension{Ext: ext, Seq: seq}}, nil
+}
+
+// matchType returns the first TypeRule that matches a file with name,
+// type typ and DirEntry d, which may be nil. The permission bits of regular
+// files, which fs.DirEntry.Type does not report, are read with d.Info the
+// first time a rule needs them.
+func (c *LSColors) matchType(name string, typ fs.FileMode, d fs.DirEntry) *ColorExtension {
+	resolved := d == nil || !typ.IsRegular() || typ&fs.ModePerm != 0
+	for i := range c.TypeRules {
+		r := &c.TypeRules[i]
+		if !resolved && (r.Type == TypeExecutable || r.Type == TypeNonExecutable) {
+			typ, resolved = entryMode(typ, d), true
+		}
+		if r.Type.match(typ) && r.MatchExt(name) {
+			return &r.ColorExtension
+		}
+	}
+	return nil
+}
+
+// entryMode returns the mode of the regular file d, whose type is typ,
+// including the permission bits that fs.DirEntry.Type does not report. It
+// returns typ if d.Info fails.
+func entryMode(typ fs.FileMode, d fs.DirEntry) fs.FileMode {
+	if fi, err := d.Info(); err == nil {
+		return fi.Mode()
+	}
+	return typ
+}
@@ -0,0 +1,86 @@
+package lscolors
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestTypeRules(t *testing.T) {
+	dir := makeTestTree(t, "conf.d/", "conf/")
+	files := []struct {
+		name string
+		mode os.FileMode
+	}{
+		{"build.sh", 0755},
+		{"lib.sh", 0644},
+		{"file.d", 0644},
+	}
+	for _, f := range files {
+		path := filepath.Join(dir, f.name)
+		if err := os.WriteFile(path, nil, f.mode); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chmod(path, f.mode); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if fi, err := os.Stat(filepath.Join(dir, "build.sh")); err != nil {
+		t.Fatal(err)
+	} else if fi.Mode()&0111 == 0 {
+		t.Skip("file system does not support executable bits")
+	}
+
+	ls, err := ParseLSColors("di=01;34:*.sh=33")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, x := range []struct {
+		typ      FileType
+		ext, seq string
+	}{
+		{TypeExecutable, "*.sh", "01;33"},
+		{TypeNonExecutable, ".sh", "02;33"},
+		{TypeDir, ".d", "04;34"},
+	} {
+		r, err := NewTypeRule(x.typ, x.ext, x.seq)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ls.TypeRules = append(ls.TypeRules, r)
+	}
+	want := map[string]string{
+		"build.sh": "01;33",
+		"lib.sh":   "02;33",
+		"conf.d":   "04;34",
+		"conf":     "01;34",
+		"file.d":   "",
+	}
+	// Real DirEntries only report the type bits of a file's mode.
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != len(want) {
+		t.Fatalf("ReadDir returned %d entries; want: %d", len(entries), len(want))
+	}
+	for _, d := range entries {
+		path := filepath.Join(dir, d.Name())
+		if e := ls.MatchEntry(path, d); e.Seq != want[d.Name()] {
+			t.Errorf("MatchEntry(%q) = %q; want: %q", d.Name(), e.Seq, want[d.Name()])
+		}
+		fi, err := os.Lstat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if e := ls.MatchInfo(path, fi); e.Seq != want[d.Name()] {
+			t.Errorf("MatchInfo(%q) = %q; want: %q", d.Name(), e.Seq, want[d.Name()])
+		}
+	}
+
+	for _, x := range []struct{ ext, seq string }{{"", "01"}, {"*", "01"}, {".sh", "x"}} {
+		if _, err := NewTypeRule(TypeRegular, x.ext, x.seq); err == nil {
+			t.Errorf("NewTypeRule(%q, %q): expected an error", x.ext, x.seq)
+		}
+	}
+}
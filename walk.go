@@ -0,0 +1,41 @@
+package lscolors
+
+import (
+	"io"
+	"io/fs"
+)
+
+// WalkDirFunc returns a function for fs.WalkDir and filepath.WalkDir that
+// writes the path of every entry, formatted by AppendPath, followed by a
+// newline to w.
+//
+// Unlike a plain WalkDirFunc(w), it takes the FS being walked since the
+// paths passed to the callback by fs.WalkDir are relative to it: symbolic
+// links, which must be stat'd to detect if they are broken, are resolved
+// in fsys so that a link in an fstest.MapFS or embed.FS is not looked up
+// in the current directory. fsys must be nil when walking with
+// filepath.WalkDir, whose paths name files of the operating system.
+//
+// Errors reading a directory are ignored so that the rest of the tree is
+// still walked, but an error for the root or from writing to w stops the
+// walk and is returned by WalkDir. Each entry is written with a single call
+// to Write so w may be a bufio.Writer, which the caller must flush once the
+// walk is complete.
+func (c *LSColors) WalkDirFunc(fsys fs.FS, w io.Writer) fs.WalkDirFunc {
+	var buf []byte
+	return func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if d == nil {
+				return err // the root could not be read
+			}
+			return nil
+		}
+		if fsys != nil {
+			d = fsEntry{d, fsys, path}
+		}
+		buf = c.AppendPath(buf[:0], path, d)
+		buf = append(buf, '\n')
+		_, err = w.Write(buf)
+		return err
+	}
+}
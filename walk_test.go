@@ -0,0 +1,119 @@
+package lscolors
+
+import (
+	"bufio"
+	"errors"
+	"io/fs"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestWalkDirFunc(t *testing.T) {
+	fsys := fstest.MapFS{
+		"a.go":       {},
+		"b/c.go":     {},
+		"b/d/e.txt":  {},
+		"b/d/f.tar":  {},
+		"empty":      {Mode: fs.ModeDir},
+		"README.txt": {},
+	}
+	ls, err := ParseLSColors("di=01;34:*.go=32:*.tar=31")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sb strings.Builder
+	w := bufio.NewWriter(&sb)
+	if err := fs.WalkDir(fsys, ".", ls.WalkDirFunc(fsys, w)); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	di := func(s string) string { return "\x1b[01;34m" + s + "\x1b[0m" }
+	want := []string{
+		di("."),
+		"README.txt",
+		"\x1b[32ma.go\x1b[0m",
+		di("b"),
+		di("b/") + "\x1b[32mc.go\x1b[0m",
+		di("b/") + di("d"),
+		di("b/d/") + "e.txt",
+		di("b/d/") + "\x1b[31mf.tar\x1b[0m",
+		di("empty"),
+	}
+	if got, want := sb.String(), strings.Join(want, "\n")+"\n"; got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestWalkDirFuncSeparatorColor(t *testing.T) {
+	fsys := fstest.MapFS{"a/b.go": {}}
+	ls, err := ParseLSColors("di=01;34:*.go=32")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ls.SeparatorColor = &ColorExtension{Seq: "02"}
+	var sb strings.Builder
+	if err := fs.WalkDir(fsys, "a", ls.WalkDirFunc(fsys, &sb)); err != nil {
+		t.Fatal(err)
+	}
+	var want []byte
+	for _, p := range []string{"a", "a/b.go"} {
+		d, err := fs.Stat(fsys, p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want = ls.AppendPath(want, p, fs.FileInfoToDirEntry(d))
+		want = append(want, '\n')
+	}
+	if got := sb.String(); got != string(want) {
+		t.Errorf("got:  %q\nwant: %q", got, want)
+	}
+	if !strings.Contains(sb.String(), "\x1b[02m/\x1b[0m") {
+		t.Errorf("got: %q; want the separator colored by SeparatorColor", sb.String())
+	}
+}
+
+func TestWalkDirFuncSymlink(t *testing.T) {
+	fsys := fstest.MapFS{
+		"target": {},
+		"link":   {Mode: fs.ModeSymlink, Data: []byte("target")},
+	}
+	ls, err := ParseLSColors("ln=01;36:or=31")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sb strings.Builder
+	if err := fs.WalkDir(fsys, ".", ls.WalkDirFunc(fsys, &sb)); err != nil {
+		t.Fatal(err)
+	}
+	const want = ".\n\x1b[01;36mlink\x1b[0m\ntarget\n"
+	if got := sb.String(); got != want {
+		t.Errorf("got: %q; want: %q", got, want)
+	}
+}
+
+type errWriter struct{ err error }
+
+func (w errWriter) Write([]byte) (int, error) { return 0, w.err }
+
+func TestWalkDirFuncErrors(t *testing.T) {
+	ls, err := ParseLSColors("di=01;34")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fsys := fstest.MapFS{"a": {}}
+	if err := fs.WalkDir(fsys, "missing", ls.WalkDirFunc(fsys, &strings.Builder{})); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("WalkDir(missing) = %v; want: %v", err, fs.ErrNotExist)
+	}
+	werr := errors.New("write error")
+	if err := fs.WalkDir(fsys, ".", ls.WalkDirFunc(fsys, errWriter{werr})); err != werr {
+		t.Errorf("WalkDir = %v; want: %v", err, werr)
+	}
+	// Errors reading a directory are skipped
+	fn := ls.WalkDirFunc(fsys, &strings.Builder{})
+	if err := fn("dir", testDirEntry{name: "dir", mode: fs.ModeDir}, fs.ErrPermission); err != nil {
+		t.Errorf("fn(dir, ErrPermission) = %v; want: nil", err)
+	}
+}
@@ -0,0 +1,18 @@
+//go:build linux
+
+package lscolors
+
+import (
+	"io/fs"
+	"syscall"
+)
+
+// isWhiteout reports if fi is an overlayfs whiteout: a character device
+// with device number 0:0.
+func isWhiteout(fi fs.FileInfo) bool {
+	if fi.Mode()&fs.ModeCharDevice == 0 {
+		return false
+	}
+	st, ok := fi.Sys().(*syscall.Stat_t)
+	return ok && st.Rdev == 0
+}
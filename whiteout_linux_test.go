@@ -0,0 +1,77 @@
+//go:build linux
+
+package lscolors
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// devInfo is a FileInfo for a device with device number rdev.
+type devInfo struct {
+	name string
+	mode fs.FileMode
+	rdev uint64
+}
+
+func (fi devInfo) Name() string       { return fi.name }
+func (fi devInfo) Size() int64        { return 0 }
+func (fi devInfo) Mode() fs.FileMode  { return fi.mode }
+func (fi devInfo) ModTime() time.Time { return time.Time{} }
+func (fi devInfo) IsDir() bool        { return false }
+func (fi devInfo) Sys() any           { return &syscall.Stat_t{Rdev: fi.rdev} }
+
+func TestWhiteoutColor(t *testing.T) {
+	ls, err := ParseLSColors("cd=01;33:bd=01;33:*.go=32")
+	if err != nil {
+		t.Fatal(err)
+	}
+	whiteout := devInfo{name: "deleted.go", mode: fs.ModeDevice | fs.ModeCharDevice}
+	null := devInfo{name: "null", mode: fs.ModeDevice | fs.ModeCharDevice, rdev: 1<<8 | 3}
+	block := devInfo{name: "sda", mode: fs.ModeDevice}
+
+	// Disabled by default
+	if e := ls.MatchInfo("deleted.go", whiteout); e != &ls.CD {
+		t.Errorf("MatchInfo(whiteout) = %+v; want: %+v", e, ls.CD)
+	}
+	ls.WhiteoutColor = ColorExtension{Ext: "whiteout", Seq: "09;31"}
+	tests := []struct {
+		fi   devInfo
+		want *ColorExtension
+	}{
+		{whiteout, &ls.WhiteoutColor},
+		{null, &ls.CD},
+		{block, &ls.BD},
+	}
+	for _, x := range tests {
+		if e := ls.MatchInfo(x.fi.name, x.fi); e != x.want {
+			t.Errorf("MatchInfo(%q) = %+v; want: %+v", x.fi.name, e, x.want)
+		}
+		d := fs.FileInfoToDirEntry(x.fi)
+		if e := ls.MatchEntry(x.fi.name, d); e != x.want {
+			t.Errorf("MatchEntry(%q) = %+v; want: %+v", x.fi.name, e, x.want)
+		}
+	}
+	if _, key := ls.MatchEntryKey("deleted.go", fs.FileInfoToDirEntry(whiteout)); key != "whiteout" {
+		t.Errorf("MatchEntryKey(whiteout) = %q; want: %q", key, "whiteout")
+	}
+}
+
+func TestWhiteoutColorMknod(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "whiteout")
+	if err := syscall.Mknod(name, syscall.S_IFCHR, 0); err != nil {
+		t.Skip("creating a whiteout requires CAP_MKNOD:", err)
+	}
+	entries, err := os.ReadDir(filepath.Dir(name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ls := &LSColors{WhiteoutColor: ColorExtension{Ext: "whiteout", Seq: "09"}}
+	if e := ls.MatchEntry(name, entries[0]); e != &ls.WhiteoutColor {
+		t.Errorf("MatchEntry = %+v; want: %+v", e, ls.WhiteoutColor)
+	}
+}
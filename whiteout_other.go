@@ -0,0 +1,11 @@
+//go:build !linux
+
+package lscolors
+
+import "io/fs"
+
+// isWhiteout reports if fi is a whiteout. Whiteouts are only detected on
+// Linux.
+func isWhiteout(fi fs.FileInfo) bool {
+	return false
+}
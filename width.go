@@ -0,0 +1,143 @@
+package lscolors
+
+import (
+	"sort"
+	"unicode"
+	"unicode/utf8"
+)
+
+// wideRanges are the ranges of runes with an East Asian Width of Wide or
+// Fullwidth, which occupy two columns of a terminal, including the emoji
+// that terminals display as wide.
+var wideRanges = [...]struct{ lo, hi rune }{
+	{0x1100, 0x115f},   // Hangul Jamo
+	{0x231a, 0x231b},   // watch, hourglass
+	{0x2329, 0x232a},   // angle brackets
+	{0x23e9, 0x23ec},   // media controls
+	{0x23f0, 0x23f0},   // alarm clock
+	{0x23f3, 0x23f3},   // hourglass
+	{0x25fd, 0x25fe},   // medium small squares
+	{0x2614, 0x2615},   // umbrella, hot beverage
+	{0x2648, 0x2653},   // zodiac
+	{0x267f, 0x267f},   // wheelchair
+	{0x2693, 0x2693},   // anchor
+	{0x26a1, 0x26a1},   // high voltage
+	{0x26aa, 0x26ab},   // circles
+	{0x26bd, 0x26be},   // soccer ball, baseball
+	{0x26c4, 0x26c5},   // snowman, sun behind cloud
+	{0x26ce, 0x26ce},   // ophiuchus
+	{0x26d4, 0x26d4},   // no entry
+	{0x26ea, 0x26ea},   // church
+	{0x26f2, 0x26f3},   // fountain, golf
+	{0x26f5, 0x26f5},   // sailboat
+	{0x26fa, 0x26fa},   // tent
+	{0x26fd, 0x26fd},   // fuel pump
+	{0x2705, 0x2705},   // check mark
+	{0x270a, 0x270b},   // fists
+	{0x2728, 0x2728},   // sparkles
+	{0x274c, 0x274c},   // cross mark
+	{0x274e, 0x274e},   // cross mark
+	{0x2753, 0x2755},   // question marks
+	{0x2757, 0x2757},   // exclamation mark
+	{0x2795, 0x2797},   // plus, minus, divide
+	{0x27b0, 0x27b0},   // curly loop
+	{0x27bf, 0x27bf},   // double curly loop
+	{0x2b1b, 0x2b1c},   // large squares
+	{0x2b50, 0x2b50},   // star
+	{0x2b55, 0x2b55},   // circle
+	{0x2e80, 0x303e},   // CJK radicals, symbols and punctuation
+	{0x3041, 0x33ff},   // Hiragana, Katakana, Bopomofo, CJK compatibility
+	{0x3400, 0x4dbf},   // CJK Unified Ideographs Extension A
+	{0x4e00, 0x9fff},   // CJK Unified Ideographs
+	{0xa000, 0xa4cf},   // Yi
+	{0xa960, 0xa97f},   // Hangul Jamo Extended-A
+	{0xac00, 0xd7a3},   // Hangul Syllables
+	{0xf900, 0xfaff},   // CJK Compatibility Ideographs
+	{0xfe10, 0xfe19},   // vertical forms
+	{0xfe30, 0xfe6f},   // CJK compatibility forms, small form variants
+	{0xff00, 0xff60},   // fullwidth forms
+	{0xffe0, 0xffe6},   // fullwidth signs
+	{0x16fe0, 0x16fe4}, // ideographic symbols
+	{0x17000, 0x18cff}, // Tangut
+	{0x1b000, 0x1b2ff}, // Kana supplement and extensions, Nushu
+	{0x1f004, 0x1f004}, // mahjong tile
+	{0x1f0cf, 0x1f0cf}, // playing card
+	{0x1f18e, 0x1f18e}, // AB button
+	{0x1f191, 0x1f19a}, // squared words
+	{0x1f200, 0x1f2ff}, // enclosed ideographic supplement
+	{0x1f300, 0x1f320}, // weather and landscapes
+	{0x1f32d, 0x1f335}, // food
+	{0x1f337, 0x1f37c}, // plants and food
+	{0x1f37e, 0x1f393}, // food and celebration
+	{0x1f3a0, 0x1f3ca}, // activities
+	{0x1f3cf, 0x1f3d3}, // sports
+	{0x1f3e0, 0x1f3f0}, // buildings
+	{0x1f3f4, 0x1f3f4}, // black flag
+	{0x1f3f8, 0x1f43e}, // sports and animals
+	{0x1f440, 0x1f440}, // eyes
+	{0x1f442, 0x1f4fc}, // people and objects
+	{0x1f4ff, 0x1f53d}, // objects
+	{0x1f54b, 0x1f54e}, // religious symbols
+	{0x1f550, 0x1f567}, // clock faces
+	{0x1f57a, 0x1f57a}, // man dancing
+	{0x1f595, 0x1f596}, // hand gestures
+	{0x1f5a4, 0x1f5a4}, // black heart
+	{0x1f5fb, 0x1f64f}, // places and emoticons
+	{0x1f680, 0x1f6c5}, // transport
+	{0x1f6cc, 0x1f6cc}, // sleeping accommodation
+	{0x1f6d0, 0x1f6d2}, // transport
+	{0x1f6d5, 0x1f6d7}, // places
+	{0x1f6dc, 0x1f6df}, // transport
+	{0x1f6eb, 0x1f6ec}, // airplanes
+	{0x1f6f4, 0x1f6fc}, // transport
+	{0x1f7e0, 0x1f7eb}, // geometric shapes
+	{0x1f7f0, 0x1f7f0}, // heavy equals sign
+	{0x1f90c, 0x1f93a}, // supplemental symbols
+	{0x1f93c, 0x1f945}, // supplemental symbols
+	{0x1f947, 0x1f9ff}, // supplemental symbols
+	{0x1fa70, 0x1faff}, // symbols and pictographs extended-A
+	{0x20000, 0x2fffd}, // CJK Unified Ideographs Extension B-F
+	{0x30000, 0x3fffd}, // CJK Unified Ideographs Extension G-H
+}
+
+// runeWidth returns the number of terminal columns occupied by r: 0 for
+// control, combining and format characters, 2 for wide East Asian
+// characters and 1 otherwise.
+func runeWidth(r rune) int {
+	switch {
+	case r < 0x20 || 0x7f <= r && r < 0xa0:
+		return 0
+	case r < 0x300:
+		return 1 // fast path for ASCII and Latin-1
+	case unicode.In(r, unicode.Mn, unicode.Me, unicode.Cf):
+		return 0
+	case r < wideRanges[0].lo:
+		return 1
+	}
+	i := sort.Search(len(wideRanges), func(i int) bool {
+		return wideRanges[i].hi >= r
+	})
+	if i < len(wideRanges) && wideRanges[i].lo <= r {
+		return 2
+	}
+	return 1
+}
+
+// stringWidth returns the number of terminal columns occupied by s, which
+// must not contain escape sequences.
+func stringWidth(s string) int {
+	n := 0
+	for i := 0; i < len(s); {
+		if c := s[i]; c < utf8.RuneSelf {
+			if c >= 0x20 && c != 0x7f {
+				n++
+			}
+			i++
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		n += runeWidth(r)
+		i += size
+	}
+	return n
+}
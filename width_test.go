@@ -0,0 +1,27 @@
+package lscolors
+
+import "testing"
+
+func TestStringWidth(t *testing.T) {
+	tests := []struct {
+		s     string
+		width int
+	}{
+		{"", 0},
+		{"main.go", 7},
+		{"café", 4},
+		{"cafe\u0301", 4}, // combining acute accent
+		{"日本語.go", 9},
+		{"한국어", 6},
+		{"ｆｕｌｌ", 8},     // fullwidth forms
+		{"a\u200bb", 2}, // zero width space
+		{"🚀.txt", 6},
+		{"\x00\x7f\u0085", 0},
+		{"\xff", 1}, // invalid UTF-8 is displayed as U+FFFD
+	}
+	for _, x := range tests {
+		if n := stringWidth(x.s); n != x.width {
+			t.Errorf("stringWidth(%q) = %d; want: %d", x.s, n, x.width)
+		}
+	}
+}